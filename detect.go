@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/teerapap/mangafmt/internal/book"
+	"github.com/teerapap/mangafmt/internal/imgutil"
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+type spreadReport struct {
+	Input       string              `json:"input"`
+	Pages       string              `json:"pages"`
+	Pairs       []book.SpreadResult `json:"pairs"`
+	SpreadForce string              `json:"spreadForce"` // connected pairs in --spread-force format
+}
+
+// detectSpreads runs only double-page spread detection over every adjacent pair of pages in the page range
+func detectSpreads(theBook *book.Book, pr *book.PageRange) (spreadReport, error) {
+	report := spreadReport{Input: theBook.Filepath, Pages: strings.Trim(pr.String(), "[]"), Pairs: []book.SpreadResult{}}
+	pageLog := func() *log.Logger {
+		if verbose {
+			return log.Default()
+		}
+		// the report has all measurements so the detection logs are discarded with the page
+		return log.NewBuffer()
+	}
+
+	var prev *book.Page
+	for i, pageNo := range pr.All() {
+		log.Printf("Detecting page %d....(%d/%d)", pageNo, i+1, pr.PageCount())
+		current, err := theBook.LoadPage(pageNo, pageLog())
+		if err != nil {
+			return report, fmt.Errorf("loading page %d: %w", pageNo, err)
+		}
+
+		if prev != nil && prev.PageNo+1 == pageNo {
+			left, right := prev.LeftRight(current)
+			res, err := left.DetectSpread(right, spreadConfig)
+			if err != nil {
+				return report, fmt.Errorf("checking if two pages(%d and %d) are double-page spread: %w", prev.PageNo, pageNo, err)
+			}
+			report.Pairs = append(report.Pairs, res)
+		}
+		if prev != nil {
+			prev.Destroy()
+		}
+		prev = current
+	}
+	if prev != nil {
+		prev.Destroy()
+	}
+
+	// pair pages in order like the conversion does
+	pairs := make([]string, 0)
+	last := 0
+	for _, res := range report.Pairs {
+		first, second := min(res.Left, res.Right), max(res.Left, res.Right)
+		if res.Connected && first > last {
+			pairs = append(pairs, fmt.Sprintf("%d-%d", first, second))
+			last = second
+		}
+	}
+	report.SpreadForce = strings.Join(pairs, ", ")
+	return report, nil
+}
+
+func printSpreadReport(report spreadReport) error {
+	if reportFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Left\tRight\tBackground Distortion (left/right/threshold)\tLR Distortion (threshold)\tGradient Correlation\tConfidence\tDecision")
+	for _, res := range report.Pairs {
+		bgs := make([]string, 0, len(res.BgDistortions))
+		for _, bg := range res.BgDistortions {
+			bgs = append(bgs, fmt.Sprintf("%s %.4f/%.4f/%.2f", bg.Color, bg.Left, bg.Right, bg.Threshold))
+		}
+		lr := "-"
+		if res.LrDistortion >= 0 {
+			lr = fmt.Sprintf("%.4f (%.2f)", res.LrDistortion, res.LrThreshold)
+			if res.Alignment.OffsetY != 0 || res.Alignment.Scale != 1 {
+				lr += fmt.Sprintf(" offset=%d scale=%.3f", res.Alignment.OffsetY, res.Alignment.Scale)
+			}
+			if res.Alignment.Overlap > 0 {
+				lr += fmt.Sprintf(" overlap=%d", res.Alignment.Overlap)
+			}
+		}
+		decision := "single"
+		if res.Connected {
+			decision = "spread"
+		}
+		corr, confidence := "-", "-"
+		if res.LrDistortion >= 0 {
+			corr, confidence = fmt.Sprintf("%.4f", res.GradientCorr), fmt.Sprintf("%.4f", res.Confidence)
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s (%s)\n", res.Left, res.Right, strings.Join(bgs, ", "), lr, corr, confidence, decision, res.Decision)
+	}
+	w.Flush()
+
+	log.Printf("Spread detection report of %s in page range %s", report.Input, report.Pages)
+	log.Indent()
+	for _, line := range strings.Split(strings.TrimRight(sb.String(), "\n"), "\n") {
+		log.Printf("%s", line)
+	}
+	log.Unindent()
+	log.Printf("--spread-force \"%s\"", report.SpreadForce)
+	return nil
+}
+
+// defaultBgColors are used if no background color is detected
+var defaultBgColors = []color.Color{color.White, color.Black}
+
+// detectBackground sets the book background colors detected from the border of sampled pages.
+// It logs the detected colors and the thresholds they imply.
+func detectBackground(theBook *book.Book, pr *book.PageRange) error {
+	log.Printf("Detecting background colors")
+	log.Indent()
+	defer log.Unindent()
+
+	clusters, err := theBook.DetectBackground(pr)
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		theBook.Config.BgColor = defaultBgColors
+		log.Printf("[Background] No dominant border color. Using default background colors")
+		return nil
+	}
+
+	theBook.Config.BgColor = make([]color.Color, 0, len(clusters))
+	colors := make([]string, 0, len(clusters))
+	distortions := make([]string, 0, len(clusters))
+	minFuzz := 0.0
+	for _, c := range clusters {
+		theBook.Config.BgColor = append(theBook.Config.BgColor, c.Color)
+		colors = append(colors, imgutil.ToHexString(c.Color))
+		log.Printf("[Background] %s covers %.2f%% of border - color spread %.4f", imgutil.ToHexString(c.Color), c.Share*100, c.Spread)
+		if len(distortions) == 0 {
+			minFuzz = c.Spread
+		}
+		// distortion of an edge of all background color against the color (with the alpha channel)
+		distortions = append(distortions, fmt.Sprintf("%.4f", c.Spread*math.Sqrt(3.0/4.0)))
+	}
+	log.Printf("[Background] Detected background colors: %s", strings.Join(colors, ","))
+	log.Printf("[Background] Implied minimum thresholds: --fuzz %.4f --spread-bg-distortion %s", minFuzz, strings.Join(distortions, ","))
+	if fuzzP < minFuzz {
+		log.Printf("[Background] --fuzz %.4f is below the implied minimum %.4f. Background noise may stop trimming", fuzzP, minFuzz)
+	}
+	for i, d := range spreadConfig.BgDistort[:min(len(clusters), len(spreadConfig.BgDistort))] {
+		if d < clusters[i].Spread*math.Sqrt(3.0/4.0) {
+			log.Printf("[Background] --spread-bg-distortion %.4f of %s is below the implied minimum. Background edges may not be detected", d, colors[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+//
+// archive.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"archive/zip"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+
+	"github.com/teerapap/mangafmt/internal/log"
+	"github.com/teerapap/mangafmt/internal/util"
+)
+
+type archiveSource struct {
+	path  string
+	r     *zip.ReadCloser
+	files []*zip.File
+}
+
+func (s *archiveSource) Open(filepath string, config BookConfig) error {
+	r, err := zip.OpenReader(filepath)
+	if err != nil {
+		return fmt.Errorf("opening input archive file: %w", err)
+	}
+
+	files := make([]*zip.File, 0, len(r.File))
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if isHiddenEntry(f.Name) || !isImageFile(f.Name) {
+			log.Verbosef("Skip non-image entry %s", f.Name)
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		r.Close()
+		return fmt.Errorf("no image files found in input archive file %s", filepath)
+	}
+	slices.SortFunc(files, func(a, b *zip.File) int {
+		return util.NaturalCompare(a.Name, b.Name)
+	})
+
+	s.path = filepath
+	s.r = r
+	s.files = files
+	return nil
+}
+
+// isHiddenEntry checks if any part of the entry path is hidden (ex. __MACOSX/, .DS_Store)
+func isHiddenEntry(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *archiveSource) PageCount() int {
+	return len(s.files)
+}
+
+func (s *archiveSource) Title() string {
+	return ""
+}
+
+func (s *archiveSource) ReadDirection() ReadDirection {
+	return UnknownDirection
+}
+
+func (s *archiveSource) LoadPage(pageNo int, l *log.Logger) (image.Image, error) {
+	entry := s.files[pageNo-1]
+	f, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening archive entry %s: %w", entry.Name, err)
+	}
+	defer f.Close()
+
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("loading archive entry %s: %w", entry.Name, err)
+	}
+	l.Verbosef("Loaded page %d from archive entry %s with format=%s, size=%s", pageNo, entry.Name, format, img.Bounds())
+	return img, nil
+}
+
+func (s *archiveSource) Close() error {
+	return s.r.Close()
+}
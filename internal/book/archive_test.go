@@ -0,0 +1,57 @@
+//
+// archive_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"testing"
+)
+
+func TestArchiveSource(t *testing.T) {
+	path := writeTestZip(t, "book.cbz", []zipEntry{
+		{"Vol1/page10.png", encodeTestPng(t, 10, 5)},
+		{"Vol1/", nil},
+		{"Vol1/page2.png", encodeTestPng(t, 2, 5)},
+		{"__MACOSX/Vol1/._page1.png", []byte("resource fork")},
+		{"Vol1/.DS_Store", []byte("finder")},
+		{"ComicInfo.xml", []byte("<ComicInfo/>")},
+		{"Vol1/page1.jpg.png", encodeTestPng(t, 1, 5)},
+	})
+
+	src := &archiveSource{}
+	if err := src.Open(path, BookConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	checkPageWidths(t, src, 1, 2, 10)
+}
+
+func TestArchiveSourceWithoutImages(t *testing.T) {
+	path := writeTestZip(t, "book.cbz", []zipEntry{
+		{"ComicInfo.xml", []byte("<ComicInfo/>")},
+		{"__MACOSX/._page1.png", []byte("resource fork")},
+	})
+	if err := (&archiveSource{}).Open(path, BookConfig{}); err == nil {
+		t.Errorf("got no error for an archive without images")
+	}
+}
+
+func TestIsHiddenEntry(t *testing.T) {
+	tests := map[string]bool{
+		"page1.png":                false,
+		"Vol.01/page1.png":         false,
+		".page1.png":               true,
+		"Vol.01/.DS_Store":         true,
+		"__MACOSX/Vol.01/page.png": true,
+		".git/page.png":            true,
+	}
+	for name, want := range tests {
+		if got := isHiddenEntry(name); got != want {
+			t.Errorf("isHiddenEntry(%s) = %t, want %t", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+//
+// background.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/teerapap/mangafmt/internal/imgutil"
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+const backgroundSamplePages = 8   // maximum number of pages to sample
+const minBackgroundShare = 0.1    // minimum ratio of border pixels to be a background color
+const maxBackgroundColors = 3     // maximum number of background colors
+const backgroundClusterFuzz = 0.1 // maximum color distance within a cluster (percentage)
+const backgroundBorderP = 0.01    // border thickness (percentage of page size)
+
+// DetectBackground samples the border pixels of pages evenly spread in the page range and clusters them.
+// It returns the dominant background colors sorted by their shares.
+// The first page is skipped if there are other pages because it is usually a full-color cover.
+func (b *Book) DetectBackground(pr *PageRange) ([]imgutil.ColorCluster, error) {
+	pageNos := pr.All()
+	if len(pageNos) > 1 && pageNos[0] == 1 {
+		pageNos = pageNos[1:]
+	}
+	samples := make([]int, 0, backgroundSamplePages)
+	for i := 0; i < min(len(pageNos), backgroundSamplePages); i++ {
+		samples = append(samples, pageNos[i*len(pageNos)/min(len(pageNos), backgroundSamplePages)])
+	}
+	log.Verbosef("[Background] Sampling border of pages %v", samples)
+
+	hist := imgutil.NewColorHistogram()
+	for _, pageNo := range samples {
+		page, err := b.LoadPage(pageNo, log.Default())
+		if err != nil {
+			return nil, fmt.Errorf("loading page %d: %w", pageNo, err)
+		}
+		r := page.img.Bounds()
+		thickness := max(1, int(backgroundBorderP*float64(min(r.Dx(), r.Dy()))))
+		hist.AddRect(page.img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+thickness))                     // top
+		hist.AddRect(page.img, image.Rect(r.Min.X, r.Max.Y-thickness, r.Max.X, r.Max.Y))                     // bottom
+		hist.AddRect(page.img, image.Rect(r.Min.X, r.Min.Y+thickness, r.Min.X+thickness, r.Max.Y-thickness)) // left
+		hist.AddRect(page.img, image.Rect(r.Max.X-thickness, r.Min.Y+thickness, r.Max.X, r.Max.Y-thickness)) // right
+		page.Destroy()
+	}
+
+	clusters := hist.Clusters(backgroundClusterFuzz, minBackgroundShare)
+	if len(clusters) > maxBackgroundColors {
+		clusters = clusters[:maxBackgroundColors]
+	}
+	return clusters, nil
+}
@@ -0,0 +1,40 @@
+//
+// blank.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+
+	"github.com/teerapap/mangafmt/internal/imgutil"
+)
+
+type BlankConfig struct {
+	Remove       bool
+	FuzzP        float64
+	MaxCoverageP float64 // maximum ratio of non-background pixels of a blank page
+}
+
+// IsBlank returns true if the page is almost all the main background color
+func (p Page) IsBlank(cfg BlankConfig) (bool, error) {
+	bgColor := p.book.Config.BgColor[0]
+	r, err := imgutil.TrimRect(p.img, bgColor, cfg.FuzzP)
+	if err != nil {
+		return false, fmt.Errorf("finding trim box: %w", err)
+	}
+	if r.Empty() {
+		p.log.Verbosef("[Blank] Page is entirely background color")
+		return true, nil
+	}
+	if cfg.MaxCoverageP <= 0 {
+		return false, nil
+	}
+
+	coverage := imgutil.ForegroundRatio(p.img, bgColor, cfg.FuzzP)
+	p.log.Verbosef("[Blank] Non-background coverage: %.4f%% (max: %.4f%%)", coverage*100, cfg.MaxCoverageP*100)
+	return coverage <= cfg.MaxCoverageP, nil
+}
@@ -0,0 +1,50 @@
+//
+// blank_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func testBlankPage(marks ...image.Rectangle) *Page {
+	p := newTestPage(newTestBook(false, color.White), 1, 100, 100)
+	for _, r := range marks {
+		fillRect(p, r, color.Black)
+	}
+	return p
+}
+
+func TestIsBlank(t *testing.T) {
+	cfg := BlankConfig{Remove: true, FuzzP: 0.1, MaxCoverageP: 0.001}
+	tests := []struct {
+		name string
+		page *Page
+		want bool
+	}{
+		{"background only", testBlankPage(), true},
+		{"scanner dust", testBlankPage(image.Rect(10, 10, 13, 13)), true},
+		{"content", testBlankPage(image.Rect(10, 10, 30, 30)), false},
+	}
+	for _, tt := range tests {
+		got, err := tt.page.IsBlank(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+
+	// no coverage allowed
+	cfg.MaxCoverageP = 0
+	if got, _ := testBlankPage(image.Rect(10, 10, 13, 13)).IsBlank(cfg); got {
+		t.Errorf("scanner dust without coverage: got blank, want not blank")
+	}
+}
@@ -0,0 +1,104 @@
+//
+// dir.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/teerapap/mangafmt/internal/log"
+	"github.com/teerapap/mangafmt/internal/util"
+)
+
+var imageExts = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".tif", ".tiff"}
+
+func isImageFile(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, ".") { // hidden files
+		return false
+	}
+	return slices.Contains(imageExts, strings.ToLower(filepath.Ext(base)))
+}
+
+func hasImageFiles(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, fmt.Errorf("reading input directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && isImageFile(entry.Name()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type imageDirSource struct {
+	path  string
+	files []string
+}
+
+func (s *imageDirSource) Open(path string, config BookConfig) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("reading input directory: %w", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !isImageFile(entry.Name()) {
+			log.Verbosef("Skip non-image file %s", entry.Name())
+			continue
+		}
+		files = append(files, entry.Name())
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no image files found in input directory %s", path)
+	}
+	slices.SortFunc(files, util.NaturalCompare)
+
+	s.path = path
+	s.files = files
+	return nil
+}
+
+func (s *imageDirSource) PageCount() int {
+	return len(s.files)
+}
+
+func (s *imageDirSource) Title() string {
+	return ""
+}
+
+func (s *imageDirSource) ReadDirection() ReadDirection {
+	return UnknownDirection
+}
+
+func (s *imageDirSource) LoadPage(pageNo int, l *log.Logger) (image.Image, error) {
+	filename := filepath.Join(s.path, s.files[pageNo-1])
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening image file %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("loading image file %s: %w", filename, err)
+	}
+	l.Verbosef("Loaded page %d from file %s with format=%s, size=%s", pageNo, filename, format, img.Bounds())
+	return img, nil
+}
+
+func (s *imageDirSource) Close() error {
+	return nil
+}
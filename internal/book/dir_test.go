@@ -0,0 +1,69 @@
+//
+// dir_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+func TestImageDirSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"page10.png":  encodeTestPng(t, 10, 5),
+		"page2.PNG":   encodeTestPng(t, 2, 5),
+		"page1.png":   encodeTestPng(t, 1, 5),
+		".hidden.png": encodeTestPng(t, 99, 5),
+		"notes.txt":   []byte("not an image"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.png"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &imageDirSource{}
+	if err := src.Open(dir, BookConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	checkPageWidths(t, src, 1, 2, 10)
+	if src.Title() != "" || src.ReadDirection() != UnknownDirection {
+		t.Errorf("got title %q and %s, want no hints", src.Title(), src.ReadDirection())
+	}
+}
+
+func TestImageDirSourceWithoutImages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&imageDirSource{}).Open(dir, BookConfig{}); err == nil {
+		t.Errorf("got no error for a directory without images")
+	}
+}
+
+func TestImageDirSourceBrokenImage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "1.png"), []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := &imageDirSource{}
+	if err := src.Open(dir, BookConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.LoadPage(1, log.NewBuffer()); err == nil {
+		t.Errorf("got no error for a broken image")
+	}
+}
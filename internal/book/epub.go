@@ -0,0 +1,240 @@
+//
+// epub.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubItem struct {
+	Id        string `xml:"id,attr"`
+	Href      string `xml:"href,attr"`
+	MediaType string `xml:"media-type,attr"`
+}
+
+type epubPackage struct {
+	Titles   []string   `xml:"metadata>title"`
+	Manifest []epubItem `xml:"manifest>item"`
+	Spine    struct {
+		Direction string `xml:"page-progression-direction,attr"`
+		ItemRefs  []struct {
+			IdRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type epubSource struct {
+	path      string
+	r         *zip.ReadCloser
+	title     string
+	direction ReadDirection
+	pages     []*zip.File // image file of each page
+}
+
+func (s *epubSource) Open(filepath string, config BookConfig) error {
+	r, err := zip.OpenReader(filepath)
+	if err != nil {
+		return fmt.Errorf("opening input epub file: %w", err)
+	}
+	s.path = filepath
+	s.r = r
+	if err := s.readPackage(); err != nil {
+		r.Close()
+		return err
+	}
+	if len(s.pages) == 0 {
+		r.Close()
+		return fmt.Errorf("no page images found in input epub file %s", filepath)
+	}
+	return nil
+}
+
+func (s *epubSource) findFile(name string) *zip.File {
+	for _, f := range s.r.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *epubSource) decodeXml(name string, v any) error {
+	f := s.findFile(name)
+	if f == nil {
+		return fmt.Errorf("%s is not found", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *epubSource) readPackage() error {
+	var container epubContainer
+	if err := s.decodeXml("META-INF/container.xml", &container); err != nil {
+		return fmt.Errorf("reading epub container: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return fmt.Errorf("reading epub container: no rootfile")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	var pkg epubPackage
+	if err := s.decodeXml(opfPath, &pkg); err != nil {
+		return fmt.Errorf("reading epub package: %w", err)
+	}
+	if len(pkg.Titles) > 0 {
+		s.title = strings.TrimSpace(pkg.Titles[0])
+	}
+	switch strings.ToLower(pkg.Spine.Direction) {
+	case "rtl":
+		s.direction = RightToLeft
+	case "ltr":
+		s.direction = LeftToRight
+	}
+
+	items := make(map[string]epubItem, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		items[item.Id] = item
+	}
+
+	// follow spine order to find the image of each page
+	opfDir := path.Dir(opfPath)
+	for _, ref := range pkg.Spine.ItemRefs {
+		item, ok := items[ref.IdRef]
+		if !ok {
+			log.Verbosef("Skip spine item %s not found in manifest", ref.IdRef)
+			continue
+		}
+		itemPath := resolveHref(opfDir, item.Href)
+
+		imgPath := itemPath
+		if !strings.HasPrefix(item.MediaType, "image/") {
+			// xhtml page referencing an image
+			src, err := s.findPageImage(itemPath)
+			if err != nil {
+				return fmt.Errorf("finding image in %s: %w", itemPath, err)
+			}
+			if src == "" {
+				log.Verbosef("Skip spine item %s without image", itemPath)
+				continue
+			}
+			imgPath = resolveHref(path.Dir(itemPath), src)
+		}
+		f := s.findFile(imgPath)
+		if f == nil {
+			return fmt.Errorf("image %s of spine item %s is not found", imgPath, itemPath)
+		}
+		s.pages = append(s.pages, f)
+	}
+	return nil
+}
+
+// findPageImage returns the first image source in the xhtml page
+func (s *epubSource) findPageImage(name string) (string, error) {
+	f := s.findFile(name)
+	if f == nil {
+		return "", fmt.Errorf("%s is not found", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	d := xml.NewDecoder(rc)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		elem, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var attr string
+		switch strings.ToLower(elem.Name.Local) {
+		case "img":
+			attr = "src"
+		case "image": // svg image
+			attr = "href"
+		default:
+			continue
+		}
+		for _, a := range elem.Attr {
+			if strings.ToLower(a.Name.Local) == attr && a.Value != "" {
+				return a.Value, nil
+			}
+		}
+	}
+}
+
+func resolveHref(dir string, href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	return strings.TrimPrefix(path.Join(dir, href), "/")
+}
+
+func (s *epubSource) PageCount() int {
+	return len(s.pages)
+}
+
+func (s *epubSource) Title() string {
+	return s.title
+}
+
+func (s *epubSource) ReadDirection() ReadDirection {
+	return s.direction
+}
+
+func (s *epubSource) LoadPage(pageNo int, l *log.Logger) (image.Image, error) {
+	entry := s.pages[pageNo-1]
+	f, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening epub entry %s: %w", entry.Name, err)
+	}
+	defer f.Close()
+
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("loading epub entry %s: %w", entry.Name, err)
+	}
+	l.Verbosef("Loaded page %d from epub entry %s with format=%s, size=%s", pageNo, entry.Name, format, img.Bounds())
+	return img, nil
+}
+
+func (s *epubSource) Close() error {
+	return s.r.Close()
+}
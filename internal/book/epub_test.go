@@ -0,0 +1,113 @@
+//
+// epub_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"testing"
+)
+
+const testEpubContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>`
+
+const testEpubPackage = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>  Test Book  </dc:title>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="p1" href="Text/p1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="p2" href="Text/p2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="p3" href="Images/c%20d.png" media-type="image/png"/>
+  </manifest>
+  <spine page-progression-direction="rtl">
+    <itemref idref="nav"/>
+    <itemref idref="p1"/>
+    <itemref idref="missing"/>
+    <itemref idref="p2"/>
+    <itemref idref="p3"/>
+  </spine>
+</package>`
+
+// testEpubEntries returns entries of an epub with an html page, an svg page and an image spine item
+func testEpubEntries(t *testing.T) []zipEntry {
+	t.Helper()
+	return []zipEntry{
+		{"mimetype", []byte("application/epub+zip")},
+		{"META-INF/container.xml", []byte(testEpubContainer)},
+		{"OEBPS/content.opf", []byte(testEpubPackage)},
+		{"OEBPS/nav.xhtml", []byte(`<html><body><nav><ol><li>Page 1</li></ol></nav></body></html>`)},
+		{"OEBPS/Text/p1.xhtml", []byte(`<!DOCTYPE html><html><head><meta charset="utf-8"></head><body><div><img src="../Images/b.png?v=1" alt="&nbsp;"></div></body></html>`)},
+		{"OEBPS/Text/p2.xhtml", []byte(`<html xmlns="http://www.w3.org/1999/xhtml"><body><svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><image width="2" height="5" xlink:href="../Images/a.png"/></svg></body></html>`)},
+		{"OEBPS/Images/a.png", encodeTestPng(t, 2, 5)},
+		{"OEBPS/Images/b.png", encodeTestPng(t, 1, 5)},
+		{"OEBPS/Images/c d.png", encodeTestPng(t, 3, 5)},
+	}
+}
+
+func TestEpubSource(t *testing.T) {
+	src := &epubSource{}
+	if err := src.Open(writeTestZip(t, "book.epub", testEpubEntries(t)), BookConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	checkPageWidths(t, src, 1, 2, 3)
+	if got := src.Title(); got != "Test Book" {
+		t.Errorf("got title %q, want Test Book", got)
+	}
+	if got := src.ReadDirection(); got != RightToLeft {
+		t.Errorf("got %s, want right-to-left", got)
+	}
+}
+
+func TestEpubSourceInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries func([]zipEntry) []zipEntry
+	}{
+		{"no container", func(entries []zipEntry) []zipEntry {
+			return append(entries[:1], entries[2:]...)
+		}},
+		{"no package", func(entries []zipEntry) []zipEntry {
+			return append(entries[:2], entries[3:]...)
+		}},
+		{"missing page image", func(entries []zipEntry) []zipEntry {
+			return entries[:len(entries)-1]
+		}},
+		{"no pages", func(entries []zipEntry) []zipEntry {
+			entries[2].data = []byte(`<package><manifest/><spine/></package>`)
+			return entries
+		}},
+	}
+	for _, tt := range tests {
+		path := writeTestZip(t, "book.epub", tt.entries(testEpubEntries(t)))
+		if err := (&epubSource{}).Open(path, BookConfig{}); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+	}
+}
+
+func TestResolveHref(t *testing.T) {
+	tests := []struct {
+		dir, href, want string
+	}{
+		{"OEBPS", "Images/a.png", "OEBPS/Images/a.png"},
+		{"OEBPS/Text", "../Images/a.png", "OEBPS/Images/a.png"},
+		{"OEBPS/Text", "../Images/a%20b.png#frag", "OEBPS/Images/a b.png"},
+		{".", "page.png", "page.png"},
+	}
+	for _, tt := range tests {
+		if got := resolveHref(tt.dir, tt.href); got != tt.want {
+			t.Errorf("resolveHref(%s, %s) = %s, want %s", tt.dir, tt.href, got, tt.want)
+		}
+	}
+}
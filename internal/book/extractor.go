@@ -0,0 +1,309 @@
+//
+// extractor.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/teerapap/mangafmt/internal/log"
+	"github.com/teerapap/mangafmt/internal/util"
+)
+
+// Intermediate image format of extracted pdf pages
+type ExtractFormat int
+
+const (
+	JPEG = iota
+	PNG
+	PNM
+	TIFF
+)
+
+func (f ExtractFormat) String() string {
+	switch f {
+	case JPEG:
+		return "jpeg"
+	case PNG:
+		return "png"
+	case PNM:
+		return "pnm"
+	case TIFF:
+		return "tiff"
+	default:
+		return "unknown"
+	}
+}
+
+func (f ExtractFormat) Ext() string {
+	switch f {
+	case JPEG:
+		return ".jpg"
+	case PNG:
+		return ".png"
+	case PNM:
+		return ".ppm"
+	case TIFF:
+		return ".tif"
+	default:
+		return ""
+	}
+}
+
+func (f *ExtractFormat) Set(val string) error {
+	switch strings.ToLower(val) {
+	case "jpeg", "jpg":
+		*f = JPEG
+	case "png":
+		*f = PNG
+	case "pnm", "ppm", "pgm":
+		*f = PNM
+	case "tiff", "tif":
+		*f = TIFF
+	default:
+		return fmt.Errorf("unknown extract format: %s", val)
+	}
+	return nil
+}
+
+type PageExtractor interface {
+	Name() string
+	Detect() error
+	Supports(format ExtractFormat) bool
+	Extract(inputFile string, page int, dpi float64, outputFile string, format ExtractFormat, l *log.Logger) error
+}
+
+type namedExtractor struct {
+	name      string
+	extractor PageExtractor
+}
+
+// External extractors in order of priority
+var externalExtractors = []namedExtractor{
+	{"vips", vips{}},
+	{"magick", imagemagick7{}},
+	{"convert", imagemagick6{}},
+	{"pdftoppm", poppler{}},
+	{"mutool", mupdf{}},
+}
+
+func ExtractorNames() []string {
+	names := []string{"auto", "native"}
+	for _, e := range externalExtractors {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+// FindExtractor finds the page extractor by name.
+// 'auto' means the native extractor with the first installed external extractor as fallback.
+func FindExtractor(name string) (PageExtractor, error) {
+	switch name {
+	case "", "auto":
+		// find by priority
+	case "native":
+		return nativePdf{}, nil
+	default:
+		for _, e := range externalExtractors {
+			if e.name != name {
+				continue
+			}
+			if err := e.extractor.Detect(); err != nil {
+				return nil, fmt.Errorf("%s is not installed: %w", e.extractor.Name(), err)
+			}
+			return e.extractor, nil
+		}
+		return nil, fmt.Errorf("unknown extractor: %s", name)
+	}
+
+	native := nativePdf{}
+	for _, e := range externalExtractors {
+		ext := e.extractor
+		if err := ext.Detect(); err != nil {
+			log.Verbosef("Cannot find %s - %s", ext.Name(), err)
+		} else {
+			// found the extractor
+			log.Verbosef("Found %s installed", ext.Name())
+			native.fallback = ext
+			break
+		}
+	}
+	if native.fallback == nil {
+		log.Verbosef("No external extractor is found. Only image-only pdf pages can be extracted.")
+	}
+
+	return native, nil
+}
+
+type imagemagick6 struct {
+}
+
+func (i imagemagick6) Name() string {
+	return "ImageMagick6"
+}
+
+func (i imagemagick6) Detect() error {
+	path, err := exec.LookPath("convert")
+	if strings.Contains(strings.ToLower(path), "system32") {
+		// Windows system convert.exe
+		return fmt.Errorf("ImageMagick6 convert utility is not found but convert.exe is found at %s", path)
+	}
+	return err
+}
+
+func (i imagemagick6) Supports(format ExtractFormat) bool {
+	return true
+}
+
+func (i imagemagick6) Extract(inputFile string, page int, dpi float64, outputFile string, format ExtractFormat, l *log.Logger) error {
+	pageFile := fmt.Sprintf("%s[%d]", inputFile, page-1)
+	cmd := exec.Command("convert", "-density", fmt.Sprintf("%0.2f", dpi), pageFile, outputFile)
+	out, err := cmd.CombinedOutput()
+	l.Verbosef("%s command: %s", i.Name(), cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	} else {
+		l.Verbosef("%s command output: %s", i.Name(), out)
+	}
+	return nil
+}
+
+type imagemagick7 struct {
+}
+
+func (i imagemagick7) Name() string {
+	return "ImageMagick7"
+}
+
+func (i imagemagick7) Detect() error {
+	_, err := exec.LookPath("magick")
+	return err
+}
+
+func (i imagemagick7) Supports(format ExtractFormat) bool {
+	return true
+}
+
+func (i imagemagick7) Extract(inputFile string, page int, dpi float64, outputFile string, format ExtractFormat, l *log.Logger) error {
+	pageFile := fmt.Sprintf("%s[%d]", inputFile, page-1)
+	cmd := exec.Command("magick", "-density", fmt.Sprintf("%0.2f", dpi), pageFile, outputFile)
+	out, err := cmd.CombinedOutput()
+	l.Verbosef("%s command: %s", i.Name(), cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	} else {
+		l.Verbosef("%s command output: %s", i.Name(), out)
+	}
+	return nil
+}
+
+type vips struct {
+}
+
+func (v vips) Name() string {
+	return "VIPS"
+}
+
+func (v vips) Detect() error {
+	_, err := exec.LookPath("vips")
+	return err
+}
+
+func (v vips) Supports(format ExtractFormat) bool {
+	return true
+}
+
+func (v vips) Extract(inputFile string, page int, dpi float64, outputFile string, format ExtractFormat, l *log.Logger) error {
+	pageFile := fmt.Sprintf("%s[page=%d,dpi=%0.2f]", inputFile, page-1, dpi)
+	cmd := exec.Command("vips", "copy", pageFile, outputFile)
+	out, err := cmd.CombinedOutput()
+	l.Verbosef("%s command: %s", v.Name(), cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	} else {
+		l.Verbosef("%s command output: %s", v.Name(), out)
+	}
+	return nil
+}
+
+type poppler struct {
+}
+
+func (p poppler) Name() string {
+	return "Poppler"
+}
+
+func (p poppler) Detect() error {
+	_, err := exec.LookPath("pdftoppm")
+	return err
+}
+
+func (p poppler) Supports(format ExtractFormat) bool {
+	return true
+}
+
+func (p poppler) Extract(inputFile string, page int, dpi float64, outputFile string, format ExtractFormat, l *log.Logger) error {
+	// pdftoppm appends file extension to the output prefix
+	outputPrefix := util.NameWithoutExt(outputFile)
+	pageNo := fmt.Sprintf("%d", page)
+	args := []string{"-f", pageNo, "-l", pageNo, "-r", fmt.Sprintf("%0.2f", dpi), "-singlefile"}
+	switch format {
+	case JPEG:
+		args = append(args, "-jpeg")
+	case PNG:
+		args = append(args, "-png")
+	case TIFF:
+		args = append(args, "-tiff")
+	case PNM:
+		// default output format
+	}
+	args = append(args, inputFile, outputPrefix)
+	cmd := exec.Command("pdftoppm", args...)
+	out, err := cmd.CombinedOutput()
+	l.Verbosef("%s command: %s", p.Name(), cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	} else {
+		l.Verbosef("%s command output: %s", p.Name(), out)
+	}
+	return nil
+}
+
+type mupdf struct {
+}
+
+func (m mupdf) Name() string {
+	return "MuPDF"
+}
+
+func (m mupdf) Detect() error {
+	_, err := exec.LookPath("mutool")
+	return err
+}
+
+// Supports returns true except TIFF. mutool cannot write JPEG so it writes PNG instead.
+func (m mupdf) Supports(format ExtractFormat) bool {
+	return format != TIFF
+}
+
+func (m mupdf) Extract(inputFile string, page int, dpi float64, outputFile string, format ExtractFormat, l *log.Logger) error {
+	if format == JPEG {
+		l.Verbosef("%s cannot write %s - writing %s instead", m.Name(), format, ExtractFormat(PNG))
+		format = PNG
+	}
+	cmd := exec.Command("mutool", "draw", "-q", "-r", fmt.Sprintf("%0.2f", dpi), "-F", format.String(), "-o", outputFile, inputFile, fmt.Sprintf("%d", page))
+	out, err := cmd.CombinedOutput()
+	l.Verbosef("%s command: %s", m.Name(), cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	} else {
+		l.Verbosef("%s command output: %s", m.Name(), out)
+	}
+	return nil
+}
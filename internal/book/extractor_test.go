@@ -0,0 +1,103 @@
+//
+// extractor_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+// fakeMutool is a mutool which records its arguments and writes page.png in its directory to the output file
+const fakeMutool = `#!/bin/sh
+PATH=/usr/bin:/bin
+dir=$(dirname "$0")
+echo "$@" > "$dir/args"
+while [ $# -gt 0 ]; do
+	if [ "$1" = "-o" ]; then
+		cp "$dir/page.png" "$2"
+	fi
+	shift
+done
+`
+
+// withOnlyMutool makes the fake mutool the only external extractor on PATH and returns its directory
+func withOnlyMutool(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake mutool is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mutool"), []byte(fakeMutool), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "page.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, 4, 6))); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+func TestFindExtractorMutoolOnly(t *testing.T) {
+	dir := withOnlyMutool(t)
+
+	extractor, err := FindExtractor("auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := extractor.Name(); got != "Native(fallback=MuPDF)" {
+		t.Fatalf("got %s, want Native(fallback=MuPDF)", got)
+	}
+	if !extractor.Supports(JPEG) {
+		t.Fatalf("%s does not support the default jpeg format", extractor.Name())
+	}
+
+	// the input is not a pdf so the page falls back to mutool which writes png instead of jpeg
+	input := filepath.Join(dir, "vector.pdf")
+	if err := os.WriteFile(input, []byte("not a pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "page.jpg")
+	if err := extractor.Extract(input, 1, 300, output, JPEG, log.NewBuffer()); err != nil {
+		t.Fatal(err)
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "-F png") {
+		t.Errorf("got mutool arguments %q, want -F png", args)
+	}
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, format, err := image.Decode(f); err != nil || format != "png" {
+		t.Errorf("got output format %s (%v), want png", format, err)
+	}
+}
+
+func TestMutoolSupports(t *testing.T) {
+	for format, want := range map[ExtractFormat]bool{JPEG: true, PNG: true, PNM: true, TIFF: false} {
+		if got := (mupdf{}).Supports(format); got != want {
+			t.Errorf("%s: got %t, want %t", format, got, want)
+		}
+	}
+}
@@ -58,6 +58,7 @@ type EpubBook struct {
 	Title            string
 	TotalPageCount   int
 	IsRTL            bool
+	Spread           string // rendition:spread
 	Contributor      string
 	Creator          string
 	ModifiedDatetime string
@@ -67,6 +68,7 @@ type EpubBook struct {
 
 type EpubPage struct {
 	Title   string
+	Spread  string // page-spread-left, page-spread-right or rendition:page-spread-center
 	BgColor string
 	Width   uint
 	Height  uint
@@ -99,6 +101,7 @@ func createEpub(theBook *book.Book, pages []Page) (EpubBook, error) {
 	epub.Creator = appVersion
 	epub.ModifiedDatetime = time.Now().Format(time.RFC3339)
 
+	epub.Spread = "portrait"
 	epub.Pages = make([]EpubPage, 0, len(pages))
 	for i, page := range pages {
 		if i == 0 {
@@ -111,6 +114,17 @@ func createEpub(theBook *book.Book, pages []Page) (EpubBook, error) {
 
 		epubPage := EpubPage{}
 		epubPage.Title = page.Id
+		switch page.Spread {
+		case SpreadLeft:
+			epubPage.Spread = "page-spread-left"
+		case SpreadRight:
+			epubPage.Spread = "page-spread-right"
+		case SpreadCenter:
+			epubPage.Spread = "rendition:page-spread-center"
+		}
+		if epubPage.Spread != "" {
+			epub.Spread = "both"
+		}
 		epubPage.BgColor = imgutil.ToHexString(theBook.Config.BgColor[0])
 		epubPage.Width = page.Size.Width
 		epubPage.Height = page.Size.Height
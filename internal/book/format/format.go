@@ -19,6 +19,12 @@ type Page struct {
 	Filepath  string
 	MediaType string
 	Size      book.Size
+
+	PageNo     int    // original page number
+	SpreadHalf int    // half number (1, 2) in reading order if this page is a half of a split spread
+	Wide       bool   // landscape page (ex. double-page spread) before fitting to screen
+	Blank      bool   // the whole page is background color
+	Spread     string // side on a two-page spread (left, right, center). Blank means unspecified
 }
 
 type OutputFormat int
@@ -0,0 +1,108 @@
+//
+// spread.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package format
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/teerapap/mangafmt/internal/book"
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+// Page sides on a two-page spread
+const (
+	SpreadLeft   = "left"
+	SpreadRight  = "right"
+	SpreadCenter = "center"
+)
+
+// ArrangeSpreads assigns the side of each page on a two-page spread.
+// The first page takes the side of its original page number. A wide page takes the whole spread.
+// Blank pages are inserted, or existing blank pages are dropped, so that both halves of a spread land on a facing pair.
+func ArrangeSpreads(theBook *book.Book, pages []Page, workDir string) ([]Page, error) {
+	if len(pages) == 0 {
+		return pages, nil
+	}
+	first, second := SpreadLeft, SpreadRight
+	if theBook.Config.IsRTL {
+		first, second = SpreadRight, SpreadLeft
+	}
+
+	log.Printf("Arranging pages on two-page spreads")
+	log.Indent()
+	defer log.Unindent()
+
+	// odd page numbers are on the second side like the cover (page 1)
+	nextFirst := pages[0].PageNo%2 == 0
+	arranged := make([]Page, 0, len(pages))
+	for _, page := range pages {
+		switch {
+		case page.Wide:
+			page.Spread = SpreadCenter
+			nextFirst = true
+		case page.SpreadHalf == 1:
+			if !nextFirst {
+				last := len(arranged) - 1
+				if last >= 0 && arranged[last].Blank && arranged[last].Spread == first {
+					log.Printf("Dropping blank page %s so that spread %s lands on facing pages", arranged[last].Id, page.Id)
+					arranged = arranged[:last]
+				} else {
+					blank, err := writeBlankPage(theBook, page, workDir)
+					if err != nil {
+						return nil, fmt.Errorf("creating blank page before %s: %w", page.Id, err)
+					}
+					log.Printf("Inserting blank page %s so that spread %s lands on facing pages", blank.Id, page.Id)
+					blank.Spread = second
+					arranged = append(arranged, blank)
+				}
+			}
+			page.Spread = first
+			nextFirst = false
+		default:
+			page.Spread = second
+			if nextFirst {
+				page.Spread = first
+			}
+			nextFirst = !nextFirst
+		}
+		arranged = append(arranged, page)
+	}
+	log.Printf("Total Output %d pages(s) after arranging.", len(arranged))
+	return arranged, nil
+}
+
+// writeBlankPage writes a blank page in the main background color with the same size as the next page
+func writeBlankPage(theBook *book.Book, next Page, workDir string) (Page, error) {
+	id := next.Id + "-blank"
+	filename := filepath.Join(workDir, id+".png")
+	img := image.NewRGBA(image.Rect(0, 0, int(next.Size.Width), int(next.Size.Height)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(theBook.Config.BgColor[0]), image.Point{}, draw.Src)
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return Page{}, fmt.Errorf("create image file %s: %w", filename, err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		return Page{}, fmt.Errorf("writing blank page to image file %s: %w", filename, err)
+	}
+
+	return Page{
+		Id:        id,
+		Filepath:  filename,
+		MediaType: "image/png",
+		Size:      next.Size,
+		PageNo:    next.PageNo,
+		Blank:     true,
+	}, nil
+}
@@ -0,0 +1,103 @@
+//
+// spread_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package format
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/teerapap/mangafmt/internal/book"
+)
+
+func testBook(isRTL bool) *book.Book {
+	return &book.Book{Config: book.BookConfig{
+		IsRTL:   isRTL,
+		BgColor: []color.Color{color.RGBA{0xf0, 0xe6, 0xc8, 0xff}},
+	}}
+}
+
+func testPage(id string, pageNo int, half int, blank bool) Page {
+	return Page{Id: id, PageNo: pageNo, SpreadHalf: half, Blank: blank, Size: book.Size{Width: 4, Height: 6}}
+}
+
+func pageIds(pages []Page) []string {
+	ids := make([]string, len(pages))
+	for i, p := range pages {
+		ids[i] = p.Id + ":" + p.Spread
+	}
+	return ids
+}
+
+func assertIds(t *testing.T, got []Page, want []string) {
+	t.Helper()
+	ids := pageIds(got)
+	if len(ids) != len(want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	for i := range ids {
+		if ids[i] != want[i] {
+			t.Fatalf("got %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestArrangeSpreadsDropsBlankPage(t *testing.T) {
+	pages := []Page{
+		testPage("page-1", 1, 0, false),
+		testPage("page-2", 2, 0, false),
+		testPage("page-3", 3, 0, false),
+		testPage("page-4", 4, 0, true),
+		testPage("page-5", 5, 1, false),
+		testPage("page-6", 6, 2, false),
+	}
+	got, err := ArrangeSpreads(testBook(false), pages, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIds(t, got, []string{"page-1:right", "page-2:left", "page-3:right", "page-5:left", "page-6:right"})
+}
+
+func TestArrangeSpreadsInsertsBlankPage(t *testing.T) {
+	pages := []Page{
+		testPage("page-1", 1, 0, false),
+		testPage("page-2", 2, 0, false),
+		testPage("page-3", 3, 1, false),
+		testPage("page-4", 4, 2, false),
+	}
+	got, err := ArrangeSpreads(testBook(true), pages, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIds(t, got, []string{"page-1:left", "page-2:right", "page-3-blank:left", "page-3:right", "page-4:left"})
+	f, err := os.Open(got[2].Filepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := img.At(0, 0).RGBA(); r>>8 != 0xf0 || g>>8 != 0xe6 || b>>8 != 0xc8 {
+		t.Errorf("blank page color = %x,%x,%x, want main background color", r>>8, g>>8, b>>8)
+	}
+
+}
+
+func TestArrangeSpreadsWidePage(t *testing.T) {
+	wide := testPage("page-2-3", 2, 0, false)
+	wide.Wide = true
+	pages := []Page{testPage("page-1", 1, 0, false), wide, testPage("page-4", 4, 0, false)}
+	got, err := ArrangeSpreads(testBook(false), pages, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIds(t, got, []string{"page-1:right", "page-2-3:center", "page-4:left"})
+}
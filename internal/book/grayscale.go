@@ -12,7 +12,6 @@ import (
 	"math"
 
 	"github.com/teerapap/mangafmt/internal/imgutil"
-	"github.com/teerapap/mangafmt/internal/log"
 )
 
 type GrayscaleConfig struct {
@@ -45,9 +44,9 @@ func (p *Page) ConvertToGrayscale(cfg GrayscaleConfig) error {
 	}
 	srcColorDepth := imgutil.ColorDepth(p.img)
 	if cfg.ColorDepth < srcColorDepth {
-		log.Printf("[Grayscale] Converting to grayscale %d-bit colors from %d-bit colors", cfg.ColorDepth, srcColorDepth)
+		p.log.Printf("[Grayscale] Converting to grayscale %d-bit colors from %d-bit colors", cfg.ColorDepth, srcColorDepth)
 	} else {
-		log.Printf("[Grayscale] Converting to grayscale while keeping %d-bit colors", srcColorDepth)
+		p.log.Printf("[Grayscale] Converting to grayscale while keeping %d-bit colors", srcColorDepth)
 	}
 	p.img = imgutil.TransformToGrayColorModel(p.img)
 	if cfg.ColorDepth < srcColorDepth { // need quantize and dither
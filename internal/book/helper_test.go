@@ -0,0 +1,98 @@
+//
+// helper_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+// newTestBook returns a book with the read direction and background colors
+func newTestBook(isRTL bool, bgColors ...color.Color) *Book {
+	return &Book{Config: BookConfig{IsRTL: isRTL, BgColor: bgColors}}
+}
+
+// newTestPage returns a page of the book with a gray image filled with the main background color if any
+func newTestPage(b *Book, pageNo int, width int, height int) *Page {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	if len(b.Config.BgColor) > 0 {
+		draw.Draw(img, img.Bounds(), image.NewUniform(b.Config.BgColor[0]), image.Point{}, draw.Src)
+	}
+	return &Page{img: img, book: b, log: log.NewBuffer(), PageNo: pageNo}
+}
+
+// fillRect fills the rectangle of the page image with the color
+func fillRect(p *Page, r image.Rectangle, c color.Color) {
+	draw.Draw(p.img.(draw.Image), r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// encodeTestPng returns a png image of the size. Tests tell pages apart by their sizes.
+func encodeTestPng(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type zipEntry struct {
+	name string
+	data []byte
+}
+
+// writeTestZip writes the entries in order to the zip file in a temp directory and returns its path
+func writeTestZip(t *testing.T, name string, entries []zipEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, e := range entries {
+		ew, err := w.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ew.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkPageWidths checks the number of pages and the width of each page image loaded from the source
+func checkPageWidths(t *testing.T, src InputSource, want ...int) {
+	t.Helper()
+	if got := src.PageCount(); got != len(want) {
+		t.Fatalf("got %d pages, want %d", got, len(want))
+	}
+	for i, width := range want {
+		img, err := src.LoadPage(i+1, log.NewBuffer())
+		if err != nil {
+			t.Fatalf("page %d: %v", i+1, err)
+		}
+		if got := img.Bounds().Dx(); got != width {
+			t.Errorf("page %d: got width %d, want %d", i+1, got, width)
+		}
+	}
+}
@@ -19,9 +19,19 @@ import (
 type Page struct {
 	img  image.Image
 	book *Book
+	log  *log.Logger
 
 	PageNo      int
 	OtherPageNo int // the other page number that this page connected with
+	Part        int // part number (1, 2) in reading order if this page is split from a wide page
+	SpreadHalf  int // half number (1, 2) in reading order if this page is a half of a split spread
+
+	joinX int // x position where two connected pages are joined
+}
+
+// SetLogger sets the logger for processing this page
+func (p *Page) SetLogger(l *log.Logger) {
+	p.log = l
 }
 
 func (p *Page) Destroy() {
@@ -46,6 +56,9 @@ func digitCount(total int) int {
 
 func (p Page) Filename(suffix string) string {
 	digits := digitCount(p.book.PageCount)
+	if p.Part > 0 { // split part (ex. page-05a)
+		suffix = string(rune('a'+p.Part-1)) + suffix
+	}
 	if p.OtherPageNo > 0 { // two-page connected
 		fileFmt := fmt.Sprintf("page-%%0%dd-%%0%dd%%s", digits, digits)
 		return fmt.Sprintf(fileFmt, p.PageNo, p.OtherPageNo, suffix)
@@ -77,7 +90,7 @@ func (p *Page) LeftRight(other *Page) (left *Page, right *Page) {
 
 func (p Page) WriteFile(dir string) (string, string, error) {
 	// Save as raw image
-	log.Printf("[Save] Writing to filesystem")
+	p.log.Printf("[Save] Writing to filesystem")
 	filename := p.Filepath(dir, ".png")
 	f, err := os.Create(filename)
 	if err != nil {
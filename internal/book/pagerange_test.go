@@ -0,0 +1,56 @@
+//
+// pagerange_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPageRangeParse(t *testing.T) {
+	tests := []struct {
+		str  string
+		want []int
+	}{
+		{"3", []int{3}},
+		{"2-3, 8-9", []int{2, 3, 8, 9}},
+		{" 1-2,5 ,7- ", []int{1, 2, 5, 7, 8, 9, 10}},
+		{"4-4", []int{4}},
+	}
+	for _, tt := range tests {
+		pr := NewPageRange()
+		if err := pr.Parse(tt.str, 10); err != nil {
+			t.Errorf("Parse(%q): %s", tt.str, err)
+			continue
+		}
+		if got := pr.All(); !slices.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestPageRangeParseError(t *testing.T) {
+	for _, str := range []string{"", "a", "0", "3-2", "11", "9-11", "1-b", "-3"} {
+		if err := NewPageRange().Parse(str, 10); err == nil {
+			t.Errorf("Parse(%q) should fail", str)
+		}
+	}
+}
+
+func TestPageRangeString(t *testing.T) {
+	pr := NewPageRange()
+	if err := pr.Parse("12-13, 88-89, 5", 100); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pr.String(), "[5, 12-13, 88-89]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if pr.First() != 5 || pr.Last() != 89 || pr.PageCount() != 5 {
+		t.Errorf("got first=%d last=%d count=%d", pr.First(), pr.Last(), pr.PageCount())
+	}
+}
@@ -0,0 +1,129 @@
+//
+// pdf.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/teerapap/mangafmt/internal/imgutil"
+	"github.com/teerapap/mangafmt/internal/log"
+	"rsc.io/pdf"
+)
+
+type pdfSource struct {
+	doc       *pdfDoc
+	density   float64
+	extractor PageExtractor
+	format    ExtractFormat
+}
+
+// pdfDoc is an opened pdf file. It is safe for concurrent use.
+type pdfDoc struct {
+	path string
+	f    *os.File
+	size int64
+	r    *pdf.Reader
+}
+
+func openPdf(path string) (*pdfDoc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening input pdf file: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("checking input pdf file size: %w", err)
+	}
+	r, err := pdf.NewReader(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading input pdf file: %w", err)
+	}
+	return &pdfDoc{path: path, f: f, size: fi.Size(), r: r}, nil
+}
+
+func (d *pdfDoc) Close() error {
+	return d.f.Close()
+}
+
+func (s *pdfSource) Open(path string, config BookConfig) error {
+	extractor, err := FindExtractor(config.Extractor)
+	if err != nil {
+		return err
+	}
+	if !extractor.Supports(config.ExtractFormat) {
+		return fmt.Errorf("%s cannot extract pages in %s format", extractor.Name(), config.ExtractFormat)
+	}
+	log.Verbosef("Using %s to extract pdf pages in %s format", extractor.Name(), config.ExtractFormat)
+
+	doc, err := openPdf(path)
+	if err != nil {
+		return err
+	}
+	if native, ok := extractor.(nativePdf); ok {
+		// reuse the opened pdf file for all pages
+		native.doc = doc
+		extractor = native
+	}
+
+	s.doc = doc
+	s.density = config.Density
+	s.extractor = extractor
+	s.format = config.ExtractFormat
+	return nil
+}
+
+func (s *pdfSource) PageCount() int {
+	return s.doc.r.NumPage()
+}
+
+func (s *pdfSource) Title() string {
+	return ""
+}
+
+func (s *pdfSource) ReadDirection() ReadDirection {
+	return UnknownDirection
+}
+
+func (s *pdfSource) LoadPage(pageNo int, l *log.Logger) (image.Image, error) {
+	// create temp directory
+	tmpFile, err := os.CreateTemp("", "mangafmt-*"+s.format.Ext())
+	if err != nil {
+		return nil, fmt.Errorf("create tmp file for input file(%s) at page %d: %w", s.doc.path, pageNo, err)
+	}
+	filename := tmpFile.Name()
+	defer os.RemoveAll(filename)
+	defer tmpFile.Close()
+
+	// extract page from pdf file
+	l.Verbosef("Loading page %d using %s", pageNo, s.extractor.Name())
+	if err = s.extractor.Extract(s.doc.path, pageNo, s.density, filename, s.format, l); err != nil {
+		return nil, fmt.Errorf("extracting pdf page to tmp file %s: %w", filename, err)
+	}
+
+	// load image file
+	img, format, err := image.Decode(tmpFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading tmp image file %s: %w", filename, err)
+	}
+	l.Verbosef("Loaded page %d at file %s with format=%s, size=%s", pageNo, filename, format, img.Bounds())
+
+	if _, isGray := img.(*image.Gray); !isGray && imgutil.ColorDepth(img) == 8 && imgutil.IsGrayscale(img) {
+		// grayscale-only page
+		img = imgutil.TransformToGrayColorModel(img)
+		l.Verbosef("Page %d is grayscale-only - converted to 8-bit gray", pageNo)
+	}
+	return img, nil
+}
+
+func (s *pdfSource) Close() error {
+	return s.doc.Close()
+}
@@ -0,0 +1,412 @@
+//
+// pdfimage.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/teerapap/mangafmt/internal/log"
+	"golang.org/x/image/ccitt"
+	"rsc.io/pdf"
+)
+
+var errNotImageOnly = errors.New("page is not image-only")
+
+// nativePdf extracts the embedded image of image-only pdf pages directly without rasterizing.
+// Pages with vector content are extracted by the fallback extractor.
+type nativePdf struct {
+	fallback PageExtractor
+	doc      *pdfDoc // opened input pdf file if any
+}
+
+func (n nativePdf) Name() string {
+	if n.fallback == nil {
+		return "Native"
+	}
+	return fmt.Sprintf("Native(fallback=%s)", n.fallback.Name())
+}
+
+func (n nativePdf) Detect() error {
+	return nil
+}
+
+// Supports returns true for all formats. Embedded images are written as is
+// and the page is extracted in PNG format if the fallback does not support the format.
+func (n nativePdf) Supports(format ExtractFormat) bool {
+	return true
+}
+
+func (n nativePdf) Extract(inputFile string, page int, dpi float64, outputFile string, format ExtractFormat, l *log.Logger) error {
+	err := n.extractImage(inputFile, page, outputFile, l)
+	if err == nil {
+		return nil
+	}
+	if n.fallback == nil {
+		return fmt.Errorf("extracting page natively: %w. One of ImageMagick, VIPS(libvips), Poppler or MuPDF is required to extract this page", err)
+	}
+	l.Verbosef("Cannot extract page %d natively (%s) - fallback to %s", page, err, n.fallback.Name())
+	if !n.fallback.Supports(format) {
+		l.Verbosef("%s cannot extract pages in %s format - using %s instead", n.fallback.Name(), format, ExtractFormat(PNG))
+		format = PNG
+	}
+	return n.fallback.Extract(inputFile, page, dpi, outputFile, format, l)
+}
+
+// extractImage extracts the page image from the opened pdf file. The input file is opened if it is not the opened one.
+func (n nativePdf) extractImage(inputFile string, page int, outputFile string, l *log.Logger) error {
+	if n.doc != nil && n.doc.path == inputFile {
+		return extractPdfImage(n.doc, page, outputFile, l)
+	}
+	doc, err := openPdf(inputFile)
+	if err != nil {
+		return err
+	}
+	defer doc.Close()
+	return extractPdfImage(doc, page, outputFile, l)
+}
+
+// Operators allowed in content stream of image-only page
+var imageOnlyOps = map[string]bool{
+	// graphics state
+	"q": true, "Q": true, "cm": true, "gs": true, "w": true, "J": true, "j": true, "M": true, "d": true, "ri": true, "i": true,
+	// path construction and clipping without painting
+	"m": true, "l": true, "c": true, "v": true, "y": true, "h": true, "re": true, "W": true, "W*": true, "n": true,
+	// color
+	"cs": true, "CS": true, "sc": true, "SC": true, "scn": true, "SCN": true, "g": true, "G": true, "rg": true, "RG": true, "k": true, "K": true,
+	// marked content
+	"MP": true, "DP": true, "BMC": true, "BDC": true, "EMC": true,
+	// xobject
+	"Do": true,
+}
+
+func extractPdfImage(doc *pdfDoc, pageNo int, outputFile string, l *log.Logger) (err error) {
+	defer func() {
+		// rsc.io/pdf panics on malformed or unsupported pdf
+		if r := recover(); r != nil {
+			err = fmt.Errorf("reading pdf: %v", r)
+		}
+	}()
+
+	r := doc.r
+	if !r.Trailer().Key("Encrypt").IsNull() {
+		return fmt.Errorf("encrypted pdf is not supported")
+	}
+
+	page := r.Page(pageNo)
+	if page.V.IsNull() {
+		return fmt.Errorf("page %d is not found", pageNo)
+	}
+	if rotate := inheritedKey(page.V, "Rotate").Int64(); rotate%360 != 0 {
+		return fmt.Errorf("rotated page (%d degrees) is not supported", rotate)
+	}
+	name, ctm, err := findPageImageName(page)
+	if err != nil {
+		return err
+	}
+	if ctm[1] != 0 || ctm[2] != 0 || ctm[0] <= 0 || ctm[3] <= 0 {
+		return fmt.Errorf("image placed with rotation or flip (matrix %v) is not supported", ctm)
+	}
+	xobj := page.Resources().Key("XObject").Key(name)
+	if xobj.Kind() != pdf.Stream || xobj.Key("Subtype").Name() != "Image" {
+		return fmt.Errorf("%w: xobject %s is not an image", errNotImageOnly, name)
+	}
+	if xobj.Key("ImageMask").Bool() || !xobj.Key("Mask").IsNull() || !xobj.Key("SMask").IsNull() {
+		return fmt.Errorf("image with mask is not supported")
+	}
+
+	if !xobj.Key("Decode").IsNull() {
+		return fmt.Errorf("image with decode array is not supported")
+	}
+
+	filter := xobj.Key("Filter")
+	params := xobj.Key("DecodeParms")
+	if filter.Kind() == pdf.Array {
+		if filter.Len() != 1 {
+			return fmt.Errorf("image with multiple filters is not supported")
+		}
+		filter = filter.Index(0)
+		params = params.Index(0)
+	}
+
+	var img image.Image
+	switch filter.Name() {
+	case "DCTDecode":
+		// pass JPEG data through untouched
+		data, err := rawStream(doc.f, doc.size, xobj)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+			return fmt.Errorf("stream data is not JPEG")
+		}
+		l.Verbosef("Extracting JPEG image %s from page %d", name, pageNo)
+		return os.WriteFile(outputFile, data, 0640)
+	case "CCITTFaxDecode":
+		data, err := rawStream(doc.f, doc.size, xobj)
+		if err != nil {
+			return err
+		}
+		if img, err = decodeCCITT(data, xobj, params); err != nil {
+			return err
+		}
+	case "FlateDecode", "":
+		// rsc.io/pdf supports only predictors of 8-bit single component images and panics on the others
+		if params.Key("Predictor").Int64() > 1 {
+			return fmt.Errorf("image with predictor %d is not supported", params.Key("Predictor").Int64())
+		}
+		data, err := io.ReadAll(xobj.Reader())
+		if err != nil {
+			return fmt.Errorf("reading image data: %w", err)
+		}
+		ncomp, err := colorComponents(xobj.Key("ColorSpace"))
+		if err != nil {
+			return err
+		}
+		width := int(xobj.Key("Width").Int64())
+		height := int(xobj.Key("Height").Int64())
+		bpc := int(xobj.Key("BitsPerComponent").Int64())
+		if img, err = decodeSamples(data, width, height, ncomp, bpc); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("image filter %s is not supported", filter.Name())
+	}
+	l.Verbosef("Extracting %s image %s from page %d", filter.Name(), name, pageNo)
+
+	// decoded image is always written losslessly in PNG format.
+	// image.Decode detects image format from its content regardless of the file extension.
+	out, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("creating output file: %w", err)
+	}
+	defer out.Close()
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	return enc.Encode(out, img)
+}
+
+// inheritedKey returns the value of the key in the page or its nearest ancestor in the page tree
+func inheritedKey(v pdf.Value, key string) pdf.Value {
+	for ; !v.IsNull(); v = v.Key("Parent") {
+		if val := v.Key(key); !val.IsNull() {
+			return val
+		}
+	}
+	return pdf.Value{}
+}
+
+// matrix is a transformation matrix [a b c d e f]
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m x n
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// findPageImageName returns the name of the only image xobject drawn in the page and its transformation matrix
+func findPageImageName(page pdf.Page) (string, matrix, error) {
+	contents := page.V.Key("Contents")
+	streams := []pdf.Value{contents}
+	if contents.Kind() == pdf.Array {
+		streams = streams[:0]
+		for i := 0; i < contents.Len(); i++ {
+			streams = append(streams, contents.Index(i))
+		}
+	}
+
+	names := make([]string, 0, 1)
+	var vectorOp string
+	ctm, imageCtm := identity, identity
+	saved := make([]matrix, 0)
+	for _, strm := range streams {
+		pdf.Interpret(strm, func(stk *pdf.Stack, op string) {
+			switch {
+			case op == "Do":
+				names = append(names, stk.Pop().Name())
+				imageCtm = ctm
+			case op == "q":
+				saved = append(saved, ctm)
+			case op == "Q" && len(saved) > 0:
+				ctm = saved[len(saved)-1]
+				saved = saved[:len(saved)-1]
+			case op == "cm" && stk.Len() >= 6:
+				var m matrix
+				for i := 5; i >= 0; i-- {
+					m[i] = stk.Pop().Float64()
+				}
+				ctm = m.mul(ctm)
+			case !imageOnlyOps[op] && vectorOp == "":
+				vectorOp = op
+			}
+			for stk.Len() > 0 {
+				stk.Pop()
+			}
+		})
+	}
+
+	if vectorOp != "" {
+		return "", imageCtm, fmt.Errorf("%w: found operator '%s'", errNotImageOnly, vectorOp)
+	} else if len(names) != 1 {
+		return "", imageCtm, fmt.Errorf("%w: found %d xobjects", errNotImageOnly, len(names))
+	}
+	return names[0], imageCtm, nil
+}
+
+// rawStream reads the stream data without decoding.
+// rsc.io/pdf neither exposes the raw stream data nor supports DCTDecode and CCITTFaxDecode filters,
+// so the data is read at the stream offset. The data must be followed by the endstream keyword.
+// Otherwise, it returns an error so that the page is extracted by the fallback extractor.
+func rawStream(f io.ReaderAt, size int64, v pdf.Value) ([]byte, error) {
+	offset, err := streamOffset(v)
+	if err != nil {
+		return nil, err
+	}
+	length := v.Key("Length").Int64()
+	if offset <= 0 || length <= 0 || offset+length > size {
+		return nil, fmt.Errorf("stream offset %d and length %d are not valid", offset, length)
+	}
+	// read a few more bytes for the endstream keyword
+	buf := make([]byte, min(length+32, size-offset))
+	if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading stream data: %w", err)
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(buf[length:], "\x00\t\n\f\r "), []byte("endstream")) {
+		return nil, fmt.Errorf("stream data at offset %d with length %d is not followed by endstream", offset, length)
+	}
+	return buf[:length], nil
+}
+
+// streamOffset returns the file offset of the stream data.
+// It is parsed from the string form of the stream value which is <<dictionary>>@offset.
+func streamOffset(v pdf.Value) (int64, error) {
+	if v.Kind() != pdf.Stream {
+		return 0, fmt.Errorf("value is not a stream")
+	}
+	str := v.String()
+	i := strings.LastIndexByte(str, '@')
+	if i < 0 {
+		return 0, fmt.Errorf("stream offset is not found")
+	}
+	offset, err := strconv.ParseInt(str[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing stream offset: %w", err)
+	}
+	return offset, nil
+}
+
+func decodeCCITT(data []byte, xobj pdf.Value, param pdf.Value) (image.Image, error) {
+
+	var sf ccitt.SubFormat
+	switch k := param.Key("K").Int64(); {
+	case k < 0:
+		sf = ccitt.Group4
+	case k == 0:
+		sf = ccitt.Group3
+	default:
+		return nil, fmt.Errorf("CCITT mixed 1D/2D encoding is not supported")
+	}
+	width := int(xobj.Key("Width").Int64())
+	height := int(xobj.Key("Height").Int64())
+	opts := ccitt.Options{
+		Align:  param.Key("EncodedByteAlign").Bool(),
+		Invert: param.Key("BlackIs1").Bool(),
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	if err := ccitt.DecodeIntoGray(img, bytes.NewReader(data), ccitt.MSB, sf, &opts); err != nil {
+		return nil, fmt.Errorf("decoding CCITT image: %w", err)
+	}
+	return img, nil
+}
+
+func colorComponents(cs pdf.Value) (int, error) {
+	switch cs.Kind() {
+	case pdf.Name:
+		switch cs.Name() {
+		case "DeviceGray", "CalGray":
+			return 1, nil
+		case "DeviceRGB", "CalRGB":
+			return 3, nil
+		case "DeviceCMYK":
+			return 4, nil
+		}
+	case pdf.Array:
+		if cs.Index(0).Name() == "ICCBased" {
+			return int(cs.Index(1).Key("N").Int64()), nil
+		}
+		return colorComponents(cs.Index(0))
+	}
+	return 0, fmt.Errorf("color space %s is not supported", cs)
+}
+
+// decodeSamples decodes uncompressed image samples with ncomp color components and bpc bits per component
+func decodeSamples(data []byte, width int, height int, ncomp int, bpc int) (image.Image, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("image size %dx%d is not valid", width, height)
+	}
+	stride := (width*ncomp*bpc + 7) / 8
+	if len(data) < stride*height {
+		return nil, fmt.Errorf("image data is too short - %d < %d", len(data), stride*height)
+	}
+	r := image.Rect(0, 0, width, height)
+
+	switch {
+	case ncomp == 1 && bpc == 1:
+		img := image.NewGray(r)
+		for y := 0; y < height; y++ {
+			row := data[y*stride:]
+			for x := 0; x < width; x++ {
+				if row[x/8]&(0x80>>(x%8)) != 0 {
+					img.Pix[y*img.Stride+x] = 0xFF
+				}
+			}
+		}
+		return img, nil
+	case ncomp == 1 && bpc == 8:
+		return &image.Gray{Pix: data, Stride: stride, Rect: r}, nil
+	case ncomp == 1 && bpc == 16:
+		return &image.Gray16{Pix: data, Stride: stride, Rect: r}, nil
+	case ncomp == 3 && bpc == 8:
+		img := image.NewRGBA(r)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				s := data[y*stride+x*3:]
+				d := img.Pix[y*img.Stride+x*4:]
+				d[0], d[1], d[2], d[3] = s[0], s[1], s[2], 0xFF
+			}
+		}
+		return img, nil
+	case ncomp == 3 && bpc == 16:
+		img := image.NewRGBA64(r)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				s := data[y*stride+x*6:]
+				d := img.Pix[y*img.Stride+x*8:]
+				copy(d, s[:6])
+				d[6], d[7] = 0xFF, 0xFF
+			}
+		}
+		return img, nil
+	case ncomp == 4 && bpc == 8:
+		return &image.CMYK{Pix: data, Stride: stride, Rect: r}, nil
+	}
+	return nil, fmt.Errorf("image with %d color components and %d bits per component is not supported", ncomp, bpc)
+}
@@ -0,0 +1,301 @@
+//
+// pdfimage_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+// writeTestPdf writes a one-page pdf with the content stream and the image xobject /Im0 and returns its path.
+// The image /Length is the length of the image data unless it is in the image dictionary.
+func writeTestPdf(t *testing.T, content string, imageDict string, imageData []byte) string {
+	t.Helper()
+	if !strings.Contains(imageDict, "/Length") {
+		imageDict += fmt.Sprintf(" /Length %d", len(imageData))
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] /Resources << /XObject << /Im0 5 0 R >> >> /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image %s >>\nstream\n%s\nendstream", imageDict, imageData),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openTestPdf(t *testing.T, path string) *pdfDoc {
+	t.Helper()
+	doc, err := openPdf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { doc.Close() })
+	return doc
+}
+
+// decodeFile decodes the image file and returns the image with its format
+func decodeFile(t *testing.T, path string) (image.Image, string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, format, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img, format
+}
+
+func TestFindPageImageName(t *testing.T) {
+	const imageDict = "/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8"
+	tests := []struct {
+		content string
+		ctm     matrix
+		err     string // empty if the page is image-only
+	}{
+		{"q 600 0 0 800 0 0 cm /Im0 Do Q", matrix{600, 0, 0, 800, 0, 0}, ""},
+		{"q 2 0 0 2 10 20 cm q 300 0 0 400 0 0 cm /Im0 Do Q Q", matrix{600, 0, 0, 800, 10, 20}, ""},
+		{"q 0 0 100 100 re W n 0 g /GS0 gs 600 0 0 800 0 0 cm /Im0 Do Q", matrix{600, 0, 0, 800, 0, 0}, ""},
+		{"/P <</MCID 0>> BDC q 600 0 0 800 0 0 cm /Im0 Do Q EMC", matrix{600, 0, 0, 800, 0, 0}, ""},
+		{"q 600 0 0 800 0 0 cm /Im0 Do Q 0 0 m 10 10 l S", identity, "found operator 'S'"},
+		{"BT /F1 12 Tf (text) Tj ET", identity, "found operator 'BT'"},
+		{"q 0 0 10 10 re f Q", identity, "found operator 'f'"},
+		{"q /Im0 Do Q q /Im0 Do Q", identity, "found 2 xobjects"},
+		{"q Q", identity, "found 0 xobjects"},
+	}
+	for _, tt := range tests {
+		doc := openTestPdf(t, writeTestPdf(t, tt.content, imageDict, []byte{0}))
+		name, ctm, err := findPageImageName(doc.r.Page(1))
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%q: got error %v", tt.content, err)
+			} else if name != "Im0" || ctm != tt.ctm {
+				t.Errorf("%q: got %s %v, want Im0 %v", tt.content, name, ctm, tt.ctm)
+			}
+		} else if !errors.Is(err, errNotImageOnly) || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got error %v, want %s", tt.content, err, tt.err)
+		}
+	}
+}
+
+func TestExtractPdfImageDCT(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 8, 6))
+	var data bytes.Buffer
+	if err := jpeg.Encode(&data, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	doc := openTestPdf(t, writeTestPdf(t, "q 80 0 0 60 0 0 cm /Im0 Do Q",
+		"/Width 8 /Height 6 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode", data.Bytes()))
+
+	output := filepath.Join(t.TempDir(), "page.jpg")
+	if err := extractPdfImage(doc, 1, output, log.NewBuffer()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data.Bytes()) {
+		t.Errorf("JPEG data is not passed through untouched")
+	}
+}
+
+func TestExtractPdfImageCCITT(t *testing.T) {
+	// Group 4 encoded 8x8 white image. Each row is vertical mode V0 followed by EOFB.
+	data := []byte{0xFF, 0x00, 0x10, 0x01}
+	tests := []struct {
+		blackIs1 bool
+		want     uint8
+	}{
+		{false, 0xFF},
+		{true, 0x00},
+	}
+	for _, tt := range tests {
+		doc := openTestPdf(t, writeTestPdf(t, "q 80 0 0 80 0 0 cm /Im0 Do Q",
+			fmt.Sprintf("/Width 8 /Height 8 /ColorSpace /DeviceGray /BitsPerComponent 1 /Filter /CCITTFaxDecode /DecodeParms << /K -1 /Columns 8 /Rows 8 /BlackIs1 %t >>", tt.blackIs1), data))
+
+		output := filepath.Join(t.TempDir(), "page.png")
+		if err := extractPdfImage(doc, 1, output, log.NewBuffer()); err != nil {
+			t.Fatal(err)
+		}
+		img, format := decodeFile(t, output)
+		if format != "png" || img.Bounds() != image.Rect(0, 0, 8, 8) {
+			t.Fatalf("got %s image with bounds %s, want 8x8 png", format, img.Bounds())
+		}
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				if got := color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y; got != tt.want {
+					t.Fatalf("BlackIs1=%t: got %d at (%d,%d), want %d", tt.blackIs1, got, x, y, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestExtractPdfImageSamples(t *testing.T) {
+	doc := openTestPdf(t, writeTestPdf(t, "q 20 0 0 10 0 0 cm /Im0 Do Q",
+		"/Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8", []byte{255, 0, 0, 0, 0, 255}))
+
+	output := filepath.Join(t.TempDir(), "page.png")
+	if err := extractPdfImage(doc, 1, output, log.NewBuffer()); err != nil {
+		t.Fatal(err)
+	}
+	img, _ := decodeFile(t, output)
+	if got := color.RGBAModel.Convert(img.At(0, 0)); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("got %v at (0,0), want red", got)
+	}
+	if got := color.RGBAModel.Convert(img.At(1, 0)); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("got %v at (1,0), want blue", got)
+	}
+}
+
+func TestExtractPdfImageUnsupported(t *testing.T) {
+	const content = "q 10 0 0 10 0 0 cm /Im0 Do Q"
+	tests := []struct {
+		content   string
+		imageDict string
+		err       string
+	}{
+		{"q -10 0 0 10 0 0 cm /Im0 Do Q", "/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8", "rotation or flip"},
+		{content, "/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /SMask 1 0 R", "mask"},
+		{content, "/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Decode [1 0]", "decode array"},
+		{content, "/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /JBIG2Decode", "JBIG2Decode"},
+		{content, "/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode", "not JPEG"},
+		{content, "/Width 1 /Height 1 /ColorSpace /Indexed /BitsPerComponent 8", "color space"},
+	}
+	for _, tt := range tests {
+		doc := openTestPdf(t, writeTestPdf(t, tt.content, tt.imageDict, []byte{0}))
+		err := extractPdfImage(doc, 1, filepath.Join(t.TempDir(), "page.png"), log.NewBuffer())
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %s", tt.imageDict, err, tt.err)
+		}
+	}
+}
+
+func TestRawStream(t *testing.T) {
+	const imageDict = "/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode"
+	data := []byte("raw stream data")
+
+	doc := openTestPdf(t, writeTestPdf(t, "/Im0 Do", imageDict, data))
+	page := doc.r.Page(1)
+	got, err := rawStream(doc.f, doc.size, page.Resources().Key("XObject").Key("Im0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got %q, want %q", got, data)
+	}
+	if _, err := rawStream(doc.f, doc.size, page.V); err == nil {
+		t.Errorf("got no error for a non-stream value")
+	}
+
+	// the stream is not followed by endstream with a wrong length
+	for _, length := range []int{len(data) - 4, len(data) + 4} {
+		doc := openTestPdf(t, writeTestPdf(t, "/Im0 Do", fmt.Sprintf("%s /Length %d", imageDict, length), data))
+		xobj := doc.r.Page(1).Resources().Key("XObject").Key("Im0")
+		if _, err := rawStream(doc.f, doc.size, xobj); err == nil || !strings.Contains(err.Error(), "endstream") {
+			t.Errorf("length %d: got error %v, want not followed by endstream", length, err)
+		}
+	}
+}
+
+func TestNativeExtractReusesOpenedPdf(t *testing.T) {
+	path := writeTestPdf(t, "q 10 0 0 10 0 0 cm /Im0 Do Q",
+		"/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8", []byte{0x80})
+	native := nativePdf{doc: openTestPdf(t, path)}
+	// the opened file is still readable after it is removed
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "page.png")
+	if err := native.Extract(path, 1, 300, output, PNG, log.NewBuffer()); err != nil {
+		t.Fatal(err)
+	}
+	if _, format := decodeFile(t, output); format != "png" {
+		t.Errorf("got %s, want png", format)
+	}
+}
+
+func TestDecodeSamples(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		width  int
+		height int
+		ncomp  int
+		bpc    int
+		x, y   int
+		want   color.Color // nil if error
+	}{
+		{"1-bit white", []byte{0b10100000, 0b01000000}, 3, 2, 1, 1, 2, 0, color.Gray{0xFF}},
+		{"1-bit black", []byte{0b10100000, 0b01000000}, 3, 2, 1, 1, 1, 0, color.Gray{0x00}},
+		{"1-bit next row", []byte{0b10100000, 0b01000000}, 3, 2, 1, 1, 1, 1, color.Gray{0xFF}},
+		{"8-bit gray", []byte{10, 20, 30, 40}, 2, 2, 1, 8, 1, 1, color.Gray{40}},
+		{"16-bit gray", []byte{0x12, 0x34, 0xAB, 0xCD}, 2, 1, 1, 16, 1, 0, color.Gray16{0xABCD}},
+		{"8-bit rgb", []byte{1, 2, 3, 4, 5, 6}, 2, 1, 3, 8, 1, 0, color.RGBA{4, 5, 6, 255}},
+		{"16-bit rgb", []byte{0, 1, 0, 2, 0, 3, 0xFF, 0xFE, 0xFF, 0xFD, 0xFF, 0xFC}, 2, 1, 3, 16, 1, 0, color.RGBA64{0xFFFE, 0xFFFD, 0xFFFC, 0xFFFF}},
+		{"8-bit cmyk", []byte{0, 0, 0, 0, 0, 255, 255, 0}, 2, 1, 4, 8, 1, 0, color.CMYK{0, 255, 255, 0}},
+		{"short data", []byte{1, 2, 3}, 2, 2, 1, 8, 0, 0, nil},
+		{"2 components", []byte{1, 2, 3, 4}, 2, 1, 2, 8, 0, 0, nil},
+		{"4-bit gray", []byte{0x12}, 2, 1, 1, 4, 0, 0, nil},
+		{"zero width", []byte{}, 0, 1, 1, 8, 0, 0, nil},
+	}
+	for _, tt := range tests {
+		img, err := decodeSamples(tt.data, tt.width, tt.height, tt.ncomp, tt.bpc)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s: got no error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if img.Bounds() != image.Rect(0, 0, tt.width, tt.height) {
+			t.Errorf("%s: got bounds %s, want %dx%d", tt.name, img.Bounds(), tt.width, tt.height)
+		}
+		gr, gg, gb, ga := img.At(tt.x, tt.y).RGBA()
+		wr, wg, wb, wa := tt.want.RGBA()
+		if gr != wr || gg != wg || gb != wb || ga != wa {
+			t.Errorf("%s: got %v at (%d,%d), want %v", tt.name, img.At(tt.x, tt.y), tt.x, tt.y, tt.want)
+		}
+	}
+}
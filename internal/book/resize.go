@@ -8,34 +8,162 @@
 package book
 
 import (
+	"fmt"
 	"image"
+	"strings"
 
 	"github.com/teerapap/mangafmt/internal/imgutil"
-	"github.com/teerapap/mangafmt/internal/log"
+	drawx "golang.org/x/image/draw"
 )
 
-func (p *Page) ResizeToFit(screen Size) error {
+// Rotation is the direction to rotate a page which does not match screen orientation
+type Rotation int
+
+const (
+	CounterClockwise = iota
+	Clockwise
+)
+
+func (r Rotation) String() string {
+	switch r {
+	case CounterClockwise:
+		return "ccw"
+	case Clockwise:
+		return "cw"
+	default:
+		return "unknown"
+	}
+}
+
+func (r *Rotation) Set(val string) error {
+	switch strings.ToLower(val) {
+	case "ccw":
+		*r = CounterClockwise
+	case "cw":
+		*r = Clockwise
+	default:
+		return fmt.Errorf("unknown rotation: %s", val)
+	}
+	return nil
+}
+
+func (r Rotation) Degree() float64 {
+	if r == Clockwise {
+		return 90
+	}
+	return 270
+}
+
+// ResizeFilter is the resampling filter to resize pages
+type ResizeFilter int
+
+const (
+	FilterCatmullRom = iota
+	FilterLanczos
+	FilterBiLinear
+)
+
+func (f ResizeFilter) String() string {
+	switch f {
+	case FilterCatmullRom:
+		return "catmullrom"
+	case FilterLanczos:
+		return "lanczos"
+	case FilterBiLinear:
+		return "bilinear"
+	default:
+		return "unknown"
+	}
+}
+
+func (f *ResizeFilter) Set(val string) error {
+	switch strings.ToLower(val) {
+	case "catmullrom":
+		*f = FilterCatmullRom
+	case "lanczos":
+		*f = FilterLanczos
+	case "bilinear":
+		*f = FilterBiLinear
+	default:
+		return fmt.Errorf("unknown resize filter: %s", val)
+	}
+	return nil
+}
+
+func (f ResizeFilter) interpolator() drawx.Interpolator {
+	switch f {
+	case FilterLanczos:
+		return imgutil.Lanczos3
+	case FilterBiLinear:
+		return drawx.BiLinear
+	default:
+		return drawx.CatmullRom
+	}
+}
+
+type ResizeConfig struct {
+	Upscale      bool // enlarge pages smaller than screen size
+	Filter       ResizeFilter
+	Sharpen      float64 // amount of unsharp mask after resizing. 0 means no sharpening
+	SharpenSigma float64 // radius of unsharp mask (pixel)
+}
+
+// resize resamples the page to the size and sharpens it
+func (p *Page) resize(size Size, cfg ResizeConfig) {
+	p.img = imgutil.ResizeWith(p.img, image.Pt(int(size.Width), int(size.Height)), cfg.Filter.interpolator())
+	if cfg.Sharpen > 0 {
+		p.log.Verbosef("[Resize] Sharpening page by amount %.2f radius %.2f", cfg.Sharpen, cfg.SharpenSigma)
+		p.img = imgutil.UnsharpMask(p.img, cfg.SharpenSigma, cfg.Sharpen)
+	}
+}
+
+func (p *Page) ResizeToFit(screen Size, rotation Rotation, cfg ResizeConfig) error {
 	pageSize := p.Size()
 	pgOrient := pageSize.Orientation()
 	scrOrient := screen.Orientation()
 	if pgOrient != Square && pgOrient != scrOrient {
-		// rotate counter-clockwise
-		log.Printf("[Resize] Rotating page because page orientation %s (%s) does not match screen orientation (%s)", pageSize, pgOrient, scrOrient)
-		p.img = imgutil.Rotate(p.img, 270)
+		p.log.Printf("[Resize] Rotating page %s because page orientation %s (%s) does not match screen orientation (%s)", rotation, pageSize, pgOrient, scrOrient)
+		p.img = imgutil.Rotate(p.img, rotation.Degree())
 
 		pageSize = p.Size()
 		//lint:ignore SA4006,SA4017 for correctness
 		pgOrient = pageSize.Orientation()
 	}
 
-	if pageSize.CanFitIn(screen) {
-		log.Printf("[Resize] Page size %s can fit in screen size %s - skip resizing", pageSize, screen)
+	if pageSize.CanFitIn(screen) && !cfg.Upscale {
+		p.log.Printf("[Resize] Page size %s can fit in screen size %s - skip resizing", pageSize, screen)
 		return nil
 	}
-	fittedSize := pageSize.AspectFitIn(screen, false)
+	fittedSize := pageSize.AspectFitIn(screen, cfg.Upscale)
+	if fittedSize == pageSize {
+		p.log.Printf("[Resize] Page size %s already fits screen size %s - skip resizing", pageSize, screen)
+		return nil
+	}
+
+	p.log.Printf("[Resize] Resizing page size %s to size %s fit in screen size %s (%s)", pageSize, fittedSize, screen, cfg.Filter)
+	p.resize(fittedSize, cfg)
+
+	return nil
+}
 
-	log.Printf("[Resize] Resizing page size %s to size %s fit in screen size %s", pageSize, fittedSize, screen)
-	p.img = imgutil.Resize(p.img, image.Pt(int(fittedSize.Width), int(fittedSize.Height)))
+// Letterbox resizes the page to fit the screen without rotation and pads it with background color to the screen aspect ratio
+func (p *Page) Letterbox(screen Size, cfg ResizeConfig) error {
+	pageSize := p.Size()
+	fittedSize := pageSize.AspectFitIn(screen, cfg.Upscale)
+	if fittedSize != pageSize {
+		p.log.Printf("[Resize] Resizing page size %s to size %s fit in screen size %s (%s)", pageSize, fittedSize, screen, cfg.Filter)
+		p.resize(fittedSize, cfg)
+	}
 
+	// the smallest box in screen aspect ratio which contains the page
+	box := Size{fittedSize.Width, fittedSize.Width * screen.Height / screen.Width}
+	if box.Height < fittedSize.Height {
+		box = Size{fittedSize.Height * screen.Width / screen.Height, fittedSize.Height}
+	}
+	if box == fittedSize {
+		return nil
+	}
+	p.log.Printf("[Resize] Letterboxing page size %s into size %s", fittedSize, box)
+	p.img = imgutil.Letterbox(p.img, image.Pt(int(box.Width), int(box.Height)), p.book.Config.BgColor[0])
 	return nil
 }
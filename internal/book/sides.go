@@ -0,0 +1,69 @@
+//
+// sides.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sides are values of top, bottom, inner (binding) and outer sides of a page.
+type Sides[T int | float64] struct {
+	Top    T
+	Bottom T
+	Inner  T
+	Outer  T
+}
+
+func AllSides[T int | float64](v T) Sides[T] {
+	return Sides[T]{v, v, v, v}
+}
+
+func (s Sides[T]) String() string {
+	if s.Top == s.Bottom && s.Top == s.Inner && s.Top == s.Outer {
+		return fmt.Sprint(s.Top)
+	}
+	return fmt.Sprintf("%v,%v,%v,%v", s.Top, s.Bottom, s.Inner, s.Outer)
+}
+
+// Set parses one value for all sides or four values of top, bottom, inner and outer sides separated by comma.
+// Values must not be negative.
+func (s *Sides[T]) Set(val string) error {
+	parts := strings.Split(val, ",")
+	if len(parts) != 1 && len(parts) != 4 {
+		return fmt.Errorf("'%s' must be one value or four values of top,bottom,inner,outer", val)
+	}
+	values := make([]T, len(parts))
+	for i, part := range parts {
+		if _, err := fmt.Sscanln(strings.TrimSpace(part), &values[i]); err != nil {
+			return fmt.Errorf("'%s' is not a number: %w", part, err)
+		}
+		if values[i] < 0 {
+			return fmt.Errorf("'%s' must not be negative", part)
+		}
+	}
+	if len(values) == 1 {
+		*s = AllSides(values[0])
+	} else {
+		*s = Sides[T]{values[0], values[1], values[2], values[3]}
+	}
+	return nil
+}
+
+// LeftRight returns values of left and right sides of the page.
+// Odd pages are on the right of a two-page spread in left-to-right books, so the inner side is on the left, and vice versa.
+// Both sides are outer sides if the page is connected or wide.
+func (s Sides[T]) LeftRight(p *Page) (left T, right T) {
+	if p.OtherPageNo > 0 || p.Size().Orientation() == Landscape {
+		return s.Outer, s.Outer
+	}
+	if (p.PageNo%2 == 1) != p.book.Config.IsRTL {
+		return s.Inner, s.Outer
+	}
+	return s.Outer, s.Inner
+}
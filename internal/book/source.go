@@ -0,0 +1,209 @@
+//
+// source.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/teerapap/mangafmt/internal/log"
+	"github.com/teerapap/mangafmt/internal/util"
+)
+
+type ReadDirection int
+
+const (
+	UnknownDirection = iota
+	LeftToRight
+	RightToLeft
+)
+
+func (d ReadDirection) String() string {
+	switch d {
+	case LeftToRight:
+		return "left-to-right"
+	case RightToLeft:
+		return "right-to-left"
+	default:
+		return "unknown"
+	}
+}
+
+// InputSource provides page images of an input book file
+type InputSource interface {
+	Open(path string, config BookConfig) error
+	PageCount() int
+	LoadPage(pageNo int, l *log.Logger) (image.Image, error)
+	// Title returns the title found in the input file or blank if unknown
+	Title() string
+	// ReadDirection returns the read direction hint found in the input file
+	ReadDirection() ReadDirection
+	Close() error
+}
+
+type InputFormat struct {
+	Name string
+	// File extensions (ex. ".pdf") to match the input file
+	Exts []string
+	// Magic bytes to match the beginning of the input file. '?' matches any byte.
+	Magic []string
+	// Match an input directory instead of a file
+	IsDir bool
+	New   func() InputSource
+}
+
+// Input formats in order of matching priority
+var inputFormats = []InputFormat{
+	{
+		Name: "EPUB",
+		Exts: []string{".epub", ".kepub"},
+		// mimetype must be the first entry in the zip file
+		Magic: []string{"PK\x03\x04??????????????????????????mimetype"},
+		New:   func() InputSource { return &epubSource{} },
+	},
+	{
+		Name:  "CBZ",
+		Exts:  []string{".cbz", ".zip"},
+		Magic: []string{"PK\x03\x04"},
+		New:   func() InputSource { return &archiveSource{} },
+	},
+	{
+		Name:  "PDF",
+		Exts:  []string{".pdf"},
+		Magic: []string{"%PDF-"},
+		New:   func() InputSource { return &pdfSource{} },
+	},
+	{
+		Name:  "Image Directory",
+		IsDir: true,
+		New:   func() InputSource { return &imageDirSource{} },
+	},
+}
+
+// RegisterInputFormat registers an additional input format.
+// It takes priority over the existing formats.
+func RegisterInputFormat(f InputFormat) {
+	inputFormats = slices.Insert(inputFormats, 0, f)
+}
+
+// FindInputFormat finds the input format of the path by file extension or magic bytes
+func FindInputFormat(path string) (InputFormat, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return InputFormat{}, fmt.Errorf("checking input file: %w", err)
+	}
+	if fi.IsDir() {
+		for _, f := range inputFormats {
+			if f.IsDir {
+				return f, nil
+			}
+		}
+		return InputFormat{}, fmt.Errorf("input directory is not supported")
+	}
+
+	// match by file extension
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range inputFormats {
+		if !f.IsDir && slices.Contains(f.Exts, ext) {
+			return f, nil
+		}
+	}
+
+	// match by magic bytes
+	header, err := readHeader(path, 64)
+	if err != nil {
+		return InputFormat{}, fmt.Errorf("reading input file header: %w", err)
+	}
+	for _, f := range inputFormats {
+		if f.IsDir {
+			continue
+		}
+		for _, magic := range f.Magic {
+			if matchMagic(magic, header) {
+				return f, nil
+			}
+		}
+	}
+	return InputFormat{}, fmt.Errorf("unsupported input file format")
+}
+
+func readHeader(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, n)
+	n, err = io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return header[:n], nil
+}
+
+func matchMagic(magic string, b []byte) bool {
+	if len(magic) > len(b) {
+		return false
+	}
+	for i, c := range []byte(magic) {
+		if c != '?' && b[i] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpandInputPath expands the path into input files.
+// A directory which contains no page images expands to the supported input files and image directories inside it.
+func ExpandInputPath(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("checking input file: %w", err)
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+	if ok, err := hasImageFiles(path); err != nil {
+		return nil, err
+	} else if ok {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading input directory: %w", err)
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		file := filepath.Join(path, entry.Name())
+		if entry.IsDir() {
+			if ok, err := hasImageFiles(file); err != nil || !ok {
+				log.Verbosef("Skip directory without image files %s", file)
+				continue
+			}
+		} else if _, err := FindInputFormat(file); err != nil {
+			log.Verbosef("Skip unsupported input file %s", file)
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no supported input files found in directory %s", path)
+	}
+	slices.SortFunc(files, util.NaturalCompare)
+	return files, nil
+}
@@ -0,0 +1,96 @@
+//
+// source_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFindInputFormat(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	epub, err := os.ReadFile(writeTestZip(t, "book.epub", testEpubEntries(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cbz, err := os.ReadFile(writeTestZip(t, "book.cbz", []zipEntry{{"1.png", encodeTestPng(t, 1, 1)}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string // empty if unsupported
+	}{
+		{write("book.EPUB", []byte("by extension")), "EPUB"},
+		{write("book.kepub", []byte("by extension")), "EPUB"},
+		{write("book.zip", []byte("by extension")), "CBZ"},
+		{write("book.pdf", []byte("by extension")), "PDF"},
+		{write("epub.bin", epub), "EPUB"},
+		{write("cbz.bin", cbz), "CBZ"},
+		{write("pdf.bin", []byte("%PDF-1.4\n")), "PDF"},
+		{write("short.bin", []byte("PK")), ""},
+		{write("text.txt", []byte("plain text")), ""},
+		{dir, "Image Directory"},
+	}
+	for _, tt := range tests {
+		f, err := FindInputFormat(tt.path)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %s, want unsupported", filepath.Base(tt.path), f.Name)
+			}
+		} else if err != nil || f.Name != tt.want {
+			t.Errorf("%s: got %s (%v), want %s", filepath.Base(tt.path), f.Name, err, tt.want)
+		}
+	}
+}
+
+func TestExpandInputPath(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"Vol.10.pdf": "%PDF-1.4\n", "Vol.2.cbz": "PK\x03\x04", "notes.txt": "plain text", ".hidden.pdf": "%PDF-1.4\n"}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sub := range []string{"Vol.3", "empty"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Vol.3", "1.png"), encodeTestPng(t, 1, 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExpandInputPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "Vol.2.cbz"), filepath.Join(dir, "Vol.3"), filepath.Join(dir, "Vol.10.pdf")}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// a directory of images is one input
+	imageDir := filepath.Join(dir, "Vol.3")
+	if got, err := ExpandInputPath(imageDir); err != nil || !slices.Equal(got, []string{imageDir}) {
+		t.Errorf("got %v (%v), want [%s]", got, err, imageDir)
+	}
+	if _, err := ExpandInputPath(filepath.Join(dir, "empty")); err == nil {
+		t.Errorf("got no error for an empty directory")
+	}
+}
@@ -0,0 +1,127 @@
+//
+// split.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"image"
+
+	"github.com/teerapap/mangafmt/internal/imgutil"
+)
+
+type SplitConfig struct {
+	Enabled     bool
+	KeepSpread  bool    // keep the whole spread after the halves
+	GutterBandP float64 // width of the band in the middle to search for gutter (percentage)[0.0-1.0]
+	MinGutterP  float64 // minimum background ratio of a gutter column (percentage)[0.0-1.0]
+}
+
+// IsWide returns true if the page is landscape while the screen is portrait
+func (p Page) IsWide(screen Size) bool {
+	return p.Size().Orientation() == Landscape && screen.Orientation() == Portrait
+}
+
+// SplitWide splits a wide page into two portrait pages at the gutter in the reading order.
+// It returns the page itself if the page is not wide.
+func (p *Page) SplitWide(cfg SplitConfig, screen Size, fuzzP float64) []*Page {
+	if !cfg.Enabled || p.OtherPageNo > 0 || !p.IsWide(screen) {
+		return []*Page{p}
+	}
+
+	splitX := p.findGutter(cfg, fuzzP)
+	pages := p.splitAt(splitX)
+	pages[0].Part, pages[1].Part = 1, 2
+
+	if cfg.KeepSpread {
+		p.log.Printf("[Split] Keeping the whole spread after the halves")
+		pages = append(pages, p)
+	}
+	return pages
+}
+
+// SplitSpread outputs the connected pages according to the spread mode
+func (p *Page) SplitSpread(mode SpreadMode) []*Page {
+	if p.OtherPageNo == 0 || (mode != SpreadSplit && mode != SpreadBoth) {
+		return []*Page{p}
+	}
+	pages := p.splitAt(p.joinX)
+	// the halves in the reading order are the original pages
+	pages[0].PageNo, pages[1].PageNo = p.PageNo, p.OtherPageNo
+	if mode == SpreadBoth {
+		p.log.Printf("[Split] Output the rotated spread followed by the split pages")
+		pages = append([]*Page{p}, pages...)
+	}
+	return pages
+}
+
+// splitAt splits the page at x position (in image coordinates) into two pages in the reading order
+func (p *Page) splitAt(splitX int) []*Page {
+	bounds := p.img.Bounds()
+	splitX = max(bounds.Min.X+1, min(splitX, bounds.Max.X-1))
+	leftRect := image.Rect(bounds.Min.X, bounds.Min.Y, splitX, bounds.Max.Y)
+	rightRect := image.Rect(splitX, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+	p.log.Printf("[Split] Splitting page %s at x=%d into left(%s) and right(%s)", p.Size(), splitX-bounds.Min.X, SizeFromBounds(leftRect), SizeFromBounds(rightRect))
+
+	left := p.part(imgutil.CropImage(p.img, leftRect))
+	right := p.part(imgutil.CropImage(p.img, rightRect))
+	pages := []*Page{left, right}
+	if p.book.Config.IsRTL {
+		pages = []*Page{right, left}
+	}
+	pages[0].SpreadHalf, pages[1].SpreadHalf = 1, 2
+	return pages
+}
+
+func (p *Page) part(img image.Image) *Page {
+	return &Page{
+		img:    img,
+		book:   p.book,
+		log:    p.log,
+		PageNo: p.PageNo,
+	}
+}
+
+// findGutter finds the x position (in image coordinates) of the gutter within the middle band.
+// The gutter is the center of the run of the most background columns nearest to the middle.
+func (p *Page) findGutter(cfg SplitConfig, fuzzP float64) int {
+	bounds := p.img.Bounds()
+	middle := bounds.Min.X + bounds.Dx()/2
+	half := int(float64(bounds.Dx()) * cfg.GutterBandP / 2)
+	if half <= 0 {
+		return middle
+	}
+	band := image.Rect(middle-half, bounds.Min.Y, middle+half, bounds.Max.Y)
+	ratios := imgutil.ColumnBackgroundRatios(p.img, band, p.book.Config.BgColor, fuzzP)
+
+	best := 0.0
+	for _, r := range ratios {
+		best = max(best, r)
+	}
+	if best < cfg.MinGutterP {
+		p.log.Verbosef("[Split] No gutter found - the most background column ratio(%f) is below threshold(%f). Splitting in the middle", best, cfg.MinGutterP)
+		return middle
+	}
+
+	// find runs of the best columns and pick the one nearest to the middle
+	gutter, distance := middle, -1
+	for start := 0; start < len(ratios); start++ {
+		if ratios[start] < best {
+			continue
+		}
+		end := start
+		for end+1 < len(ratios) && ratios[end+1] >= best {
+			end++
+		}
+		center := band.Min.X + (start+end+1)/2
+		if d := max(center-middle, middle-center); distance < 0 || d < distance {
+			gutter, distance = center, d
+		}
+		start = end
+	}
+	p.log.Verbosef("[Split] Gutter found at x=%d - background column ratio(%f)", gutter-bounds.Min.X, best)
+	return gutter
+}
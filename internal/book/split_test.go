@@ -0,0 +1,75 @@
+//
+// split_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var portraitScreen = Size{Width: 1264, Height: 1680}
+
+// testWidePage returns a 200x100 page with content on both sides of a white gutter at x=[110, 120)
+func testWidePage(isRTL bool) *Page {
+	p := newTestPage(newTestBook(isRTL, color.White), 3, 200, 100)
+	fillRect(p, image.Rect(0, 0, 110, 100), color.Black)
+	fillRect(p, image.Rect(120, 0, 200, 100), color.Black)
+	return p
+}
+
+func TestSplitWideAtGutter(t *testing.T) {
+	cfg := SplitConfig{Enabled: true, GutterBandP: 0.4, MinGutterP: 0.9}
+	pages := testWidePage(false).SplitWide(cfg, portraitScreen, 0.1)
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2", len(pages))
+	}
+	if got := pages[0].img.Bounds(); got != image.Rect(0, 0, 115, 100) {
+		t.Errorf("got left part %v, want (0,0)-(115,100)", got)
+	}
+	if got := pages[1].img.Bounds().Dx(); got != 85 {
+		t.Errorf("got right part width %d, want 85", got)
+	}
+	for i, p := range pages {
+		if p.PageNo != 3 || p.Part != i+1 || p.SpreadHalf != i+1 {
+			t.Errorf("%d: got page=%d part=%d half=%d", i, p.PageNo, p.Part, p.SpreadHalf)
+		}
+	}
+}
+
+func TestSplitWideRTL(t *testing.T) {
+	cfg := SplitConfig{Enabled: true, KeepSpread: true, GutterBandP: 0.4, MinGutterP: 0.9}
+	page := testWidePage(true)
+	pages := page.SplitWide(cfg, portraitScreen, 0.1)
+	if len(pages) != 3 {
+		t.Fatalf("got %d pages, want 2 halves and the whole spread", len(pages))
+	}
+	// the right part comes first in right-to-left reading order
+	if got := pages[0].img.Bounds().Dx(); got != 85 {
+		t.Errorf("got first part width %d, want 85", got)
+	}
+	if pages[2] != page {
+		t.Errorf("the whole spread is not kept after the halves")
+	}
+}
+
+func TestSplitWideNoGutter(t *testing.T) {
+	page := testWidePage(false)
+	fillRect(page, page.img.Bounds(), color.Black)
+	pages := page.SplitWide(SplitConfig{Enabled: true, GutterBandP: 0.4, MinGutterP: 0.9}, portraitScreen, 0.1)
+	if len(pages) != 2 || pages[0].img.Bounds().Dx() != 100 {
+		t.Errorf("got %d pages, want split in the middle", len(pages))
+	}
+}
+
+func TestSplitWideSkipsPortraitPage(t *testing.T) {
+	page := newTestPage(newTestBook(false, color.White), 1, 100, 200)
+	if pages := page.SplitWide(SplitConfig{Enabled: true}, portraitScreen, 0.1); len(pages) != 1 || pages[0] != page {
+		t.Errorf("got %d pages, want the page itself", len(pages))
+	}
+}
@@ -8,75 +8,320 @@
 package book
 
 import (
+	"fmt"
 	"image"
+	"math"
+	"strings"
 
 	"github.com/teerapap/mangafmt/internal/imgutil"
-	"github.com/teerapap/mangafmt/internal/log"
 )
 
+// SpreadMode is how a double-page spread is output
+type SpreadMode int
+
+const (
+	SpreadRotate = iota // rotate the spread to fit the screen
+	SpreadSplit         // split the spread back into two pages
+	SpreadBoth          // the rotated spread followed by two pages
+	SpreadFit           // keep landscape and letterbox into the screen
+)
+
+func (m SpreadMode) String() string {
+	switch m {
+	case SpreadRotate:
+		return "rotate"
+	case SpreadSplit:
+		return "split"
+	case SpreadBoth:
+		return "both"
+	case SpreadFit:
+		return "fit"
+	default:
+		return "unknown"
+	}
+}
+
+func (m *SpreadMode) Set(val string) error {
+	switch strings.ToLower(val) {
+	case "rotate":
+		*m = SpreadRotate
+	case "split":
+		*m = SpreadSplit
+	case "both":
+		*m = SpreadBoth
+	case "fit":
+		*m = SpreadFit
+	default:
+		return fmt.Errorf("unknown spread mode: %s", val)
+	}
+	return nil
+}
+
 type SpreadConfig struct {
-	Enabled    bool
-	EdgeWidth  uint
-	EdgeMargin uint
-	BgDistort  []float64
-	LrDistort  float64
+	Enabled        bool
+	Mode           SpreadMode
+	EdgeWidth      uint
+	EdgeMargin     uint
+	BgDistort      []float64
+	LrDistort      float64
+	MaxOffset      uint       // maximum vertical offset between two pages (pixel)
+	MaxScaleP      float64    // maximum height difference between two pages to be rescaled (percentage)[0.0-1.0]
+	MaxOverlap     uint       // maximum width of the artwork repeated on both pages (pixel). 0 means no overlap detection
+	MinConfidence  float64    // minimum confidence to connect two pages. 0 means using LrDistort threshold only
+	GradientWeight float64    // weight of the gradient correlation in the confidence [0.0-1.0]
+	Force          *PageRange // pairs of pages which are always connected
+	Never          *PageRange // pairs of pages which are never connected
+}
+
+// BgDistortion is the distortion between page edges and a background color
+type BgDistortion struct {
+	Color     string  `json:"color"`
+	Left      float64 `json:"left"`
+	Right     float64 `json:"right"`
+	Threshold float64 `json:"threshold"`
+}
+
+// SpreadAlignment is how the right page is placed next to the left page
+type SpreadAlignment struct {
+	Scale   float64 `json:"scale"`   // scale of the right page to match the left page height
+	OffsetY int     `json:"offsetY"` // vertical offset of the right page from the left page (pixel)
+	Overlap int     `json:"overlap"` // width of the artwork repeated on both pages (pixel)
+}
+
+// SpreadResult is the measurement and decision of double-page spread detection of two pages
+type SpreadResult struct {
+	Left          int             `json:"left"`
+	Right         int             `json:"right"`
+	Connected     bool            `json:"connected"`
+	Decision      string          `json:"decision"` // detected, forced, never or disabled
+	BgDistortions []BgDistortion  `json:"bgDistortions"`
+	LrDistortion  float64         `json:"lrDistortion"` // -1 if both edges cannot be compared
+	LrThreshold   float64         `json:"lrThreshold"`
+	Alignment     SpreadAlignment `json:"alignment"`
+	GradientCorr  float64         `json:"gradientCorrelation"` // correlation of the vertical gradients of both edges
+	Confidence    float64         `json:"confidence"`
+}
+
+// DetectSpread measures the edges of two pages and decides if they are double-page spread.
+// The pairs in Force or Never page range override the detection.
+func (left *Page) DetectSpread(right *Page, cfg SpreadConfig) (SpreadResult, error) {
+	res := SpreadResult{Left: left.PageNo, Right: right.PageNo, LrDistortion: -1, LrThreshold: cfg.LrDistort, Alignment: SpreadAlignment{Scale: 1}}
+	if !cfg.Enabled {
+		res.Decision = "disabled"
+	} else {
+		res.Decision = "detected"
+		res.Connected = left.detectDoublePageSpread(right, cfg, &res)
+	}
+
+	switch {
+	case cfg.Never != nil && cfg.Never.Contains(left.PageNo) && cfg.Never.Contains(right.PageNo):
+		left.log.Printf("[Spread] Page %d and %d are not connected (forced by --spread-never)", left.PageNo, right.PageNo)
+		res.Connected, res.Decision = false, "never"
+	case cfg.Force != nil && cfg.Force.Contains(left.PageNo) && cfg.Force.Contains(right.PageNo):
+		left.log.Printf("[Spread] Page %d and %d are double-page spread (forced by --spread-force)", left.PageNo, right.PageNo)
+		res.Connected, res.Decision = true, "forced"
+	case !cfg.Enabled:
+	case res.Connected:
+		left.log.Printf("[Spread] Page %d and %d are double-page spread (detected)", left.PageNo, right.PageNo)
+	default:
+		left.log.Printf("[Spread] Page %d and %d are not connected (detected)", left.PageNo, right.PageNo)
+	}
+
+	if res.Connected && res.LrDistortion >= 0 && cfg.MaxOverlap > 0 {
+		res.Alignment.Overlap = left.findOverlap(right, res.Alignment, cfg.MaxOverlap)
+	}
+	return res, nil
 }
 
-func (left *Page) IsDoublePageSpread(right *Page, cfg SpreadConfig) (bool, error) {
+func (left *Page) detectDoublePageSpread(right *Page, cfg SpreadConfig, res *SpreadResult) bool {
 	lpEdge := left.Rect().RightEdge(cfg.EdgeWidth, cfg.EdgeMargin)
 	rpEdge := right.Rect().LeftEdge(cfg.EdgeWidth, cfg.EdgeMargin)
 
-	if lpEdge.size != rpEdge.size {
-		log.Printf("[Spread] Two pages (%d and %d) are not connected because both edges are not the same size - left(%s) != right(%s)", left.PageNo, right.PageNo, lpEdge.size, rpEdge.size)
-		return false, nil
-	} else if lpEdge.size.Width == 0 {
-		log.Printf("[Spread] Two pages (%d and %d) are not connected because both pages are not wide enough - left(%s), right(%s)", left.PageNo, right.PageNo, lpEdge.size, rpEdge.size)
-		return false, nil
+	if lpEdge.size.Width == 0 || rpEdge.size.Width == 0 {
+		left.log.Printf("[Spread] Two pages (%d and %d) are not connected because both pages are not wide enough - left(%s), right(%s)", left.PageNo, right.PageNo, lpEdge.size, rpEdge.size)
+		return false
+	}
+	if lpEdge.size.Width != rpEdge.size.Width {
+		left.log.Printf("[Spread] Two pages (%d and %d) are not connected because both edges are not the same width - left(%s) != right(%s)", left.PageNo, right.PageNo, lpEdge.size, rpEdge.size)
+		return false
+	}
+	scale := float64(lpEdge.size.Height) / float64(rpEdge.size.Height)
+	if math.Abs(scale-1) > cfg.MaxScaleP {
+		left.log.Printf("[Spread] Two pages (%d and %d) are not connected because both edges are not the same size - left(%s) != right(%s)", left.PageNo, right.PageNo, lpEdge.size, rpEdge.size)
+		return false
 	}
 
-	// Prepare background canvas for comparison
+	// Measure all distortions
 	for i, bgColor := range left.book.Config.BgColor {
-		bgDistort := cfg.BgDistort[min(i, len(cfg.BgDistort)-1)]
 		bgCanvas := image.NewUniform(bgColor)
+		res.BgDistortions = append(res.BgDistortions, BgDistortion{
+			Color:     imgutil.ToHexString(bgColor),
+			Left:      imgutil.GetRMSEDistortion(left.img, lpEdge.ToRectangle(), bgCanvas, image.Point{}),
+			Right:     imgutil.GetRMSEDistortion(right.img, rpEdge.ToRectangle(), bgCanvas, image.Point{}),
+			Threshold: cfg.BgDistort[min(i, len(cfg.BgDistort)-1)],
+		})
+	}
+	res.LrDistortion, res.GradientCorr, res.Alignment = left.alignEdges(right, lpEdge, rpEdge, scale, cfg.MaxOffset)
+	res.Confidence = spreadConfidence(res.LrDistortion, res.GradientCorr, cfg.GradientWeight)
+	logComponents := left.log.Verbosef
+	if cfg.MinConfidence > 0 {
+		logComponents = left.log.Printf
+	}
+	logComponents("[Spread] Confidence(%f) of page %d and %d = edge similarity(%f) x %.2f + gradient correlation(%f) x %.2f", res.Confidence, left.PageNo, right.PageNo, 1-min(res.LrDistortion, 1), 1-cfg.GradientWeight, res.GradientCorr, cfg.GradientWeight)
+
+	for _, bg := range res.BgDistortions {
 		// Compare left vs background canvas
-		distortion := imgutil.GetRMSEDistortion(left.img, lpEdge.ToRectangle(), bgCanvas, image.Point{})
-		if distortion <= bgDistort {
+		if bg.Left <= bg.Threshold {
 			// edge is all background
-			log.Printf("[Spread] Left page(%d) edge has background border (%s) - distortion(%f) is below threshold(%f)", left.PageNo, imgutil.ToHexString(bgColor), distortion, bgDistort)
-			return false, nil
+			left.log.Printf("[Spread] Left page(%d) edge has background border (%s) - distortion(%f) is below threshold(%f)", left.PageNo, bg.Color, bg.Left, bg.Threshold)
+			return false
 		}
-		log.Verbosef("[Spread] Left page(%d) edge does not have background border (%s) - distortion(%f) is higher than threshold(%f)", left.PageNo, imgutil.ToHexString(bgColor), distortion, bgDistort)
+		left.log.Verbosef("[Spread] Left page(%d) edge does not have background border (%s) - distortion(%f) is higher than threshold(%f)", left.PageNo, bg.Color, bg.Left, bg.Threshold)
 
 		// Compare right vs background canvas
-		distortion = imgutil.GetRMSEDistortion(right.img, rpEdge.ToRectangle(), bgCanvas, image.Point{})
-		if distortion <= bgDistort {
+		if bg.Right <= bg.Threshold {
 			// edge is all background
-			log.Printf("[Spread] Right page(%d) edge has background border (%s) - distortion(%f) is below threshold(%f)", right.PageNo, imgutil.ToHexString(bgColor), distortion, bgDistort)
-			return false, nil
+			left.log.Printf("[Spread] Right page(%d) edge has background border (%s) - distortion(%f) is below threshold(%f)", right.PageNo, bg.Color, bg.Right, bg.Threshold)
+			return false
+		}
+		left.log.Verbosef("[Spread] Right page(%d) edge does not have background border (%s) - distortion(%f) is higher than threshold(%f)", right.PageNo, bg.Color, bg.Right, bg.Threshold)
+	}
+
+	if cfg.MinConfidence > 0 {
+		// Decide by the confidence
+		if res.Confidence < cfg.MinConfidence {
+			left.log.Printf("[Spread] Left page(%d) edge and right page edge(%d) do not connect - confidence(%f) is below threshold(%f)", left.PageNo, right.PageNo, res.Confidence, cfg.MinConfidence)
+			return false
 		}
-		log.Verbosef("[Spread] Right page(%d) edge does not have background border (%s) - distortion(%f) is higher than threshold(%f)", right.PageNo, imgutil.ToHexString(bgColor), distortion, bgDistort)
+		return true
 	}
 
 	// Compare left page edge vs right page edge
-	distortion := imgutil.GetRMSEDistortion(left.img, lpEdge.ToRectangle(), right.img, rpEdge.ToRectangle().Min)
-	if distortion > cfg.LrDistort {
-		log.Printf("[Spread] Left page(%d) edge and right page edge(%d) do not connect - distortion(%f) is more than threshold(%f)", left.PageNo, right.PageNo, distortion, cfg.LrDistort)
-		return false, nil
+	if res.LrDistortion > cfg.LrDistort {
+		left.log.Printf("[Spread] Left page(%d) edge and right page edge(%d) do not connect - distortion(%f) is more than threshold(%f)", left.PageNo, right.PageNo, res.LrDistortion, cfg.LrDistort)
+		return false
 	}
 	// they are double-page spread
-	log.Printf("[Spread] Page %d and %d are double-page spread! - distortion(%f) is below threshold(%f)", left.PageNo, right.PageNo, distortion, cfg.LrDistort)
+	return true
+}
+
+// alignEdges finds the vertical offset of the right page edge which matches the left page edge the most.
+// The right page edge is rescaled to the left page edge height before the comparison.
+// It also returns the correlation of the vertical gradients of both edges at the best offset.
+func (left *Page) alignEdges(right *Page, lpEdge Rect, rpEdge Rect, scale float64, maxOffset uint) (float64, float64, SpreadAlignment) {
+	lStrip := imgutil.CropImage(left.img, lpEdge.ToRectangle())
+	rStrip := imgutil.CropImage(right.img, rpEdge.ToRectangle())
+	if scale != 1 {
+		rStrip = imgutil.Resize(rStrip, image.Pt(int(rpEdge.size.Width), int(lpEdge.size.Height)))
+	}
+	lb, rb := lStrip.Bounds(), rStrip.Bounds()
+
+	best := SpreadAlignment{Scale: scale}
+	bestDistortion := -1.0
+	// search from zero offset outwards so the smaller offset wins a tie
+	for i := 0; i <= 2*int(maxOffset); i++ {
+		dy := (i + 1) / 2
+		if i%2 == 1 {
+			dy = -dy
+		}
+		distortion := stripDistortion(lStrip, lb.Min.X, rStrip, rb.Min.X, lb.Dx(), dy)
+		if distortion < 0 {
+			continue
+		}
+		if bestDistortion < 0 || distortion < bestDistortion {
+			bestDistortion = distortion
+			best.OffsetY = dy
+		}
+	}
+	if best.OffsetY != 0 || scale != 1 {
+		left.log.Verbosef("[Spread] Best alignment of right page(%d) - offset(%d) scale(%f) distortion(%f)", right.PageNo, best.OffsetY, scale, bestDistortion)
+	}
+
+	dy := best.OffsetY
+	top, bottom := max(0, dy), min(lb.Dy(), dy+rb.Dy())
+	if bottom <= top {
+		return bestDistortion, 0, best
+	}
+	lProfile := imgutil.RowProfile(lStrip, image.Rect(lb.Min.X, lb.Min.Y+top, lb.Max.X, lb.Min.Y+bottom))
+	rProfile := imgutil.RowProfile(rStrip, image.Rect(rb.Min.X, rb.Min.Y+top-dy, rb.Max.X, rb.Min.Y+bottom-dy))
+	return bestDistortion, imgutil.GradientCorrelation(lProfile, rProfile), best
+}
 
-	return true, nil
+// stripDistortion compares the columns [lx, lx+width) of the left image with the columns [rx, rx+width) of the right image
+// shifted vertically by dy. It returns -1 if they do not overlap.
+func stripDistortion(lImg image.Image, lx int, rImg image.Image, rx int, width int, dy int) float64 {
+	lb, rb := lImg.Bounds(), rImg.Bounds()
+	top, bottom := max(0, dy), min(lb.Dy(), dy+rb.Dy())
+	if bottom <= top || width <= 0 {
+		return -1
+	}
+	r := image.Rect(lx, lb.Min.Y+top, lx+width, lb.Min.Y+bottom)
+	return imgutil.GetRMSEDistortion(lImg, r, rImg, image.Pt(rx, rb.Min.Y+top-dy))
+}
+
+// findOverlap finds the width of the artwork repeated on both sides of the fold.
+// The last columns of the left page are compared with the first columns of the right page for each width up to maxOverlap.
+// The best width is accepted only if it matches much better than the other widths.
+func (left *Page) findOverlap(right *Page, alignment SpreadAlignment, maxOverlap uint) int {
+	lb, rb := left.img.Bounds(), right.img.Bounds()
+	n := min(int(maxOverlap), lb.Dx()/4, rb.Dx()/4)
+	if n < 2 {
+		return 0
+	}
+	lRegion := imgutil.CropImage(left.img, image.Rect(lb.Max.X-n, lb.Min.Y, lb.Max.X, lb.Max.Y))
+	rRegion := imgutil.CropImage(right.img, image.Rect(rb.Min.X, rb.Min.Y, rb.Min.X+n, rb.Max.Y))
+	if alignment.Scale != 0 && alignment.Scale != 1 {
+		rRegion = imgutil.Resize(rRegion, image.Pt(int(math.Round(float64(n)*alignment.Scale)), int(math.Round(float64(rb.Dy())*alignment.Scale))))
+	}
+	lrb, rrb := lRegion.Bounds(), rRegion.Bounds()
+	n = min(n, rrb.Dx())
+
+	best, bestDistortion, sum := 0, -1.0, 0.0
+	for k := 1; k <= n; k++ {
+		distortion := stripDistortion(lRegion, lrb.Max.X-k, rRegion, rrb.Min.X, k, alignment.OffsetY)
+		if distortion < 0 {
+			return 0
+		}
+		sum += distortion
+		if bestDistortion < 0 || distortion < bestDistortion {
+			best, bestDistortion = k, distortion
+		}
+	}
+	mean := sum / float64(n)
+	if best < 2 || bestDistortion >= mean/2 {
+		left.log.Verbosef("[Spread] No overlap between page %d and %d - best width(%d) distortion(%f) mean distortion(%f)", left.PageNo, right.PageNo, best, bestDistortion, mean)
+		return 0
+	}
+	left.log.Printf("[Spread] Page %d and %d overlap by %d pixel(s) - distortion(%f) mean distortion(%f)", left.PageNo, right.PageNo, best, bestDistortion, mean)
+	return best
 }
 
-func (left *Page) Connect(right *Page) (*Page, error) {
-	connected := imgutil.AppendHorizontally(left.img, right.img)
+// spreadConfidence combines the edge similarity (1 - RMSE distortion) and the positive gradient correlation by weight
+func spreadConfidence(distortion float64, gradientCorr float64, gradientWeight float64) float64 {
+	similarity := 1 - min(max(distortion, 0), 1)
+	return similarity*(1-gradientWeight) + max(gradientCorr, 0)*gradientWeight
+}
+
+func (left *Page) Connect(right *Page, alignment SpreadAlignment) (*Page, error) {
+	rightImg := right.img
+	if alignment.Scale != 0 && alignment.Scale != 1 {
+		size := right.img.Bounds().Size()
+		scaled := image.Pt(int(math.Round(float64(size.X)*alignment.Scale)), int(math.Round(float64(size.Y)*alignment.Scale)))
+		left.log.Printf("[Spread] Scaling right page(%d) from %s to %s to match left page(%d) height", right.PageNo, SizeFromBounds(right.img.Bounds()), SizeFromBounds(image.Rectangle{Max: scaled}), left.PageNo)
+		rightImg = imgutil.Resize(right.img, scaled)
+	}
+	if alignment.OffsetY != 0 {
+		left.log.Printf("[Spread] Shifting right page(%d) vertically by %d pixel(s)", right.PageNo, alignment.OffsetY)
+	}
+	connected := imgutil.AppendHorizontally(left.img, rightImg, alignment.OffsetY, alignment.Overlap, left.book.Config.BgColor[0])
 
 	newPage := &Page{
 		img:         connected,
 		book:        left.book,
+		log:         left.log,
 		PageNo:      min(left.PageNo, right.PageNo),
 		OtherPageNo: max(left.PageNo, right.PageNo),
+		joinX:       left.img.Bounds().Dx() - alignment.Overlap/2,
 	}
 	return newPage, nil
 }
@@ -0,0 +1,119 @@
+//
+// spread_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+func testSpreadConfig() SpreadConfig {
+	return SpreadConfig{
+		Enabled:    true,
+		EdgeWidth:  2,
+		EdgeMargin: 2,
+		BgDistort:  []float64{0.4, 0.2},
+		LrDistort:  0.1,
+		MaxScaleP:  0.05,
+	}
+}
+
+// testSpreadPages returns two pages whose facing edges have the same vertical gradient
+func testSpreadPages() (*Page, *Page) {
+	b := newTestBook(false, color.White, color.Black)
+	newPage := func(pageNo int) *Page {
+		p := newTestPage(b, pageNo, 100, 200)
+		for y := 0; y < 200; y++ {
+			fillRect(p, image.Rect(0, y, 100, y+1), color.Gray{uint8(60 + y/2)})
+		}
+		return p
+	}
+	return newPage(1), newPage(2)
+}
+
+func TestDetectSpreadConnected(t *testing.T) {
+	left, right := testSpreadPages()
+	res, err := left.DetectSpread(right, testSpreadConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Connected || res.Decision != "detected" {
+		t.Errorf("got connected=%t decision=%s, want connected by detection", res.Connected, res.Decision)
+	}
+}
+
+func TestDetectSpreadBackgroundEdge(t *testing.T) {
+	left, right := testSpreadPages()
+	fillRect(right, image.Rect(0, 0, 10, 200), color.White)
+	res, err := left.DetectSpread(right, testSpreadConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Connected {
+		t.Errorf("got connected, want not connected because the right page edge is background")
+	}
+}
+
+func TestDetectSpreadForceNever(t *testing.T) {
+	pair := NewPageRange()
+	pair.Add(1, 2)
+
+	left, right := testSpreadPages()
+	fillRect(right, image.Rect(0, 0, 10, 200), color.White)
+	cfg := testSpreadConfig()
+	cfg.Force = pair
+	if res, _ := left.DetectSpread(right, cfg); !res.Connected || res.Decision != "forced" {
+		t.Errorf("got connected=%t decision=%s, want forced", res.Connected, res.Decision)
+	}
+
+	left, right = testSpreadPages()
+	cfg = testSpreadConfig()
+	cfg.Never = pair
+	if res, _ := left.DetectSpread(right, cfg); res.Connected || res.Decision != "never" {
+		t.Errorf("got connected=%t decision=%s, want never", res.Connected, res.Decision)
+	}
+
+	cfg = testSpreadConfig()
+	cfg.Enabled = false
+	if res, _ := left.DetectSpread(right, cfg); res.Connected || res.Decision != "disabled" {
+		t.Errorf("got connected=%t decision=%s, want disabled", res.Connected, res.Decision)
+	}
+}
+
+func TestDetectSpreadNarrowEdges(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stdout) })
+
+	b := newTestBook(false, color.White)
+	tests := []struct {
+		leftWidth int
+		want      string
+	}{
+		{2, "not wide enough"},
+		{3, "not the same width"},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		left, right := newTestPage(b, 1, tt.leftWidth, 200), newTestPage(b, 2, 100, 200)
+		res, err := left.DetectSpread(right, testSpreadConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		left.log.Flush()
+		if res.Connected || !strings.Contains(out.String(), tt.want) {
+			t.Errorf("left width %d: got connected=%t with log %q, want not connected because %s", tt.leftWidth, res.Connected, out.String(), tt.want)
+		}
+	}
+}
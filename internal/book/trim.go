@@ -9,39 +9,189 @@ package book
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"strings"
 
 	"github.com/teerapap/mangafmt/internal/imgutil"
-	"github.com/teerapap/mangafmt/internal/log"
 )
 
+// TrimMode is how the trim box is found
+type TrimMode int
+
+const (
+	TrimEdge    = iota // stop at the first pixel which is not background
+	TrimDensity        // by ink density per row and column ignoring noise
+)
+
+func (m TrimMode) String() string {
+	switch m {
+	case TrimEdge:
+		return "edge"
+	case TrimDensity:
+		return "density"
+	default:
+		return "unknown"
+	}
+}
+
+func (m *TrimMode) Set(val string) error {
+	switch strings.ToLower(val) {
+	case "edge":
+		*m = TrimEdge
+	case "density":
+		*m = TrimDensity
+	default:
+		return fmt.Errorf("unknown trim mode: %s", val)
+	}
+	return nil
+}
+
 type TrimConfig struct {
-	Enabled  bool
-	MinSizeP float64
-	Margin   int
+	Enabled        bool
+	MinSizeP       float64
+	Margin         Sides[int]     // safety margin (pixel)
+	MaxTrimP       Sides[float64] // maximum trim of each side (percentage)[0.0-1.0]
+	Overrides      []TrimOverride // page-level overrides
+	AutoBackground bool           // trim each side against the background color found on its border
+
+	Mode         TrimMode
+	MinComponent int     // minimum pixels of connected component (density mode)
+	MinDensityP  float64 // minimum ink pixels of content row or column (density mode)(percentage)[0.0-1.0]
+	HeaderP      float64 // height of header band where content is ignored (density mode)(percentage)[0.0-1.0]
+	FooterP      float64 // height of footer band where content is ignored (density mode)(percentage)[0.0-1.0]
+	MaxEdgeLine  int     // maximum thickness of lines near the edges to be ignored (density mode)(pixel)
+
+	Uniform       UniformTrimMode
+	UniformParity bool         // separate book-wide trim boxes for odd and even pages
+	OutlierP      float64      // maximum difference of each side from the median trim box (percentage)[0.0-1.0]
+	UniformBoxes  *UniformTrim // book-wide trim boxes measured from all pages
+}
+
+// TrimBox returns the trim box with safety margin. It is empty if the page is blank.
+func (p *Page) TrimBox(cfg TrimConfig, fuzzP float64) (Rect, error) {
+	return p.trimBox(cfg.ForPage(p.PageNo), fuzzP)
+}
+
+func (p *Page) trimBox(cfg TrimConfig, fuzzP float64) (Rect, error) {
+	bgColor := p.book.Config.BgColor
+
+	var tr image.Rectangle
+	if cfg.Mode == TrimDensity {
+		bgColors := bgColor[:1]
+		if cfg.AutoBackground && len(bgColor) > 1 {
+			sides := p.borderBackgrounds(fuzzP)
+			bgColors = sides[:]
+		}
+		height := float64(p.img.Bounds().Dy())
+		tr = imgutil.TrimRectByDensity(p.img, bgColors, fuzzP, imgutil.DensityTrimOptions{
+			MinComponent: cfg.MinComponent,
+			MinDensity:   cfg.MinDensityP,
+			HeaderBand:   int(cfg.HeaderP * height),
+			FooterBand:   int(cfg.FooterP * height),
+			MaxEdgeLine:  cfg.MaxEdgeLine,
+		})
+	} else if cfg.AutoBackground && len(bgColor) > 1 {
+		tr = imgutil.TrimRectBySide(p.img, p.borderBackgrounds(fuzzP), fuzzP)
+	} else {
+		var err error
+		if tr, err = imgutil.TrimRect(p.img, bgColor[0], fuzzP); err != nil {
+			return Rect{}, fmt.Errorf("finding trim box: %w", err)
+		}
+	}
+	if tr.Empty() {
+		return Rect{}, nil
+	}
+
+	// add safety margin and bound by page rect
+	left, right := cfg.Margin.LeftRight(p)
+	tr = image.Rect(tr.Min.X-left, tr.Min.Y-cfg.Margin.Top, tr.Max.X+right, tr.Max.Y+cfg.Margin.Bottom)
+	return FromRectangle(tr).BoundBy(p.Rect()), nil
+}
+
+// limitTrim limits the trim of each side of the trim box by maximum trim
+func (p *Page) limitTrim(trimRect Rect, cfg TrimConfig) Rect {
+	pageRect := p.Rect()
+	width, height := float64(pageRect.size.Width), float64(pageRect.size.Height)
+	box := trimRect
+	if trimRect.size.Width == 0 || trimRect.size.Height == 0 {
+		// blank page. Each side is trimmed up to its maximum trim toward the page center.
+		box = Rect{origin: Point{pageRect.MinX() + int(width)/2, pageRect.MinY() + int(height)/2}}
+	}
+	maxLeft, maxRight := cfg.MaxTrimP.LeftRight(p)
+	limited := image.Rect(
+		min(box.MinX(), pageRect.MinX()+int(maxLeft*width)),
+		min(box.MinY(), pageRect.MinY()+int(cfg.MaxTrimP.Top*height)),
+		max(box.MaxX(), pageRect.MaxX()-int(maxRight*width)),
+		max(box.MaxY(), pageRect.MaxY()-int(cfg.MaxTrimP.Bottom*height)),
+	)
+	if limited != trimRect.ToRectangle() {
+		p.log.Verbosef("[Trim] trim box %s is limited by maximum trim to %s", trimRect, FromRectangle(limited))
+	}
+	return FromRectangle(limited)
+}
+
+// borderBackgrounds returns the background color of each side (top, left, bottom, right).
+// It is the background color which matches most of the border. The main background color is used if none matches the majority.
+func (p *Page) borderBackgrounds(fuzzP float64) [4]color.Color {
+	const minRatio = 0.5
+	bgColors := p.book.Config.BgColor
+	b := p.img.Bounds()
+	thickness := max(1, min(b.Dx(), b.Dy())/100)
+	borders := [4]image.Rectangle{
+		image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Min.Y+thickness), // top
+		image.Rect(b.Min.X, b.Min.Y, b.Min.X+thickness, b.Max.Y), // left
+		image.Rect(b.Min.X, b.Max.Y-thickness, b.Max.X, b.Max.Y), // bottom
+		image.Rect(b.Max.X-thickness, b.Min.Y, b.Max.X, b.Max.Y), // right
+	}
+	sides := [4]string{"top", "left", "bottom", "right"}
+
+	var res [4]color.Color
+	for i, border := range borders {
+		ratios := imgutil.BackgroundRatios(p.img, border, bgColors, fuzzP)
+		best := 0
+		for j, ratio := range ratios {
+			if ratio > ratios[best] {
+				best = j
+			}
+		}
+		if ratios[best] < minRatio {
+			best = 0
+		}
+		res[i] = bgColors[best]
+		if best != 0 {
+			p.log.Printf("[Trim] %s side is trimmed against background %s (%.2f%% of border)", sides[i], imgutil.ToHexString(res[i]), ratios[best]*100)
+		} else {
+			p.log.Verbosef("[Trim] %s side is trimmed against main background (%.2f%% of border)", sides[i], ratios[0]*100)
+		}
+	}
+	return res
 }
 
 func (p *Page) Trim(cfg TrimConfig, fuzzP float64) error {
+	cfg = cfg.ForPage(p.PageNo)
 	if !cfg.Enabled {
 		return nil
 	}
 	// Trim image with fuzz
 	pageRect := p.Rect()
 	minSize := pageRect.size.ScaleBy(cfg.MinSizeP)
-	bgColor := p.book.Config.BgColor
 
-	tr, err := imgutil.TrimRect(p.img, bgColor[0], fuzzP)
+	trimRect, err := p.trimBox(cfg, fuzzP)
 	if err != nil {
-		return fmt.Errorf("finding trim box: %w", err)
+		return err
 	}
 
-	trimRect := FromRectangle(tr).
-		InsetBy(-cfg.Margin, -cfg.Margin). // add safety margin
-		BoundBy(pageRect)                  // bound by page rect
+	p.log.Verbosef("[Trim] trim box: %s", trimRect)
 
-	log.Verbosef("[Trim] trim box: %s", trimRect)
+	if cfg.UniformBoxes != nil && p.OtherPageNo == 0 {
+		// connected pages use their own trim box
+		trimRect = cfg.UniformBoxes.Box(p, trimRect)
+	}
+	trimRect = p.limitTrim(trimRect, cfg)
 
 	if trimRect == pageRect { // trim box equals page rect
-		log.Printf("[Trim] No trimming needed")
+		p.log.Printf("[Trim] No trimming needed")
 		return nil
 	}
 
@@ -55,16 +205,20 @@ func (p *Page) Trim(cfg TrimConfig, fuzzP float64) error {
 			InsetBy(-gapX/2, -gapY/2). // expand each side to minimum size
 			MoveInside(pageRect)       // Move the rect to fit inside page rect frame as much as possible
 
-		log.Printf("[Trim] Page size %s is trimmed by %s but it is smaller than minimum size %s - expanding trim box to minimum %s", pageRect.size, oldRect, minSize, trimRect)
+		p.log.Printf("[Trim] Page size %s is trimmed by %s but it is smaller than minimum size %s - expanding trim box to minimum %s", pageRect.size, oldRect, minSize, trimRect)
 	}
 
 	// Crop to trim rectangle
 	p.img = imgutil.CropImage(p.img, trimRect.ToRectangle())
+	if p.joinX > 0 {
+		// the cropped image may not keep the same coordinates
+		p.joinX += p.img.Bounds().Min.X - trimRect.MinX()
+	}
 
 	// Print trim info
 	tWidthP := float64(trimRect.size.Width) * 100.0 / float64(pageRect.size.Width)
 	tHeightP := float64(trimRect.size.Height) * 100.0 / float64(pageRect.size.Height)
-	log.Printf("[Trim] Page size %s is trimmed by %s (%.2f%% | %.2f%%)", pageRect.size, trimRect, tWidthP, tHeightP)
+	p.log.Printf("[Trim] Page size %s is trimmed by %s (%.2f%% | %.2f%%)", pageRect.size, trimRect, tWidthP, tHeightP)
 
 	return nil
 }
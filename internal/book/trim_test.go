@@ -0,0 +1,37 @@
+//
+// trim_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"image"
+	"testing"
+)
+
+func TestLimitTrim(t *testing.T) {
+	// odd page of left-to-right book has the inner side on the left
+	page := newTestPage(newTestBook(false), 1, 100, 200)
+	maxTrim := Sides[float64]{Top: 0.1, Bottom: 0.2, Inner: 0.3, Outer: 0.4}
+	tests := []struct {
+		name     string
+		trimRect image.Rectangle
+		maxTrim  Sides[float64]
+		want     image.Rectangle
+	}{
+		{"within maximum trim", image.Rect(10, 10, 95, 190), maxTrim, image.Rect(10, 10, 95, 190)},
+		{"small content", image.Rect(45, 95, 55, 105), maxTrim, image.Rect(30, 20, 60, 160)},
+		{"blank page", image.Rectangle{}, maxTrim, image.Rect(30, 20, 60, 160)},
+		{"blank page without trim", image.Rectangle{}, AllSides(0.0), image.Rect(0, 0, 100, 200)},
+		{"blank page with full trim", image.Rectangle{}, AllSides(1.0), image.Rect(50, 100, 50, 100)},
+	}
+	for _, tt := range tests {
+		got := page.limitTrim(FromRectangle(tt.trimRect), TrimConfig{MaxTrimP: tt.maxTrim})
+		if got.ToRectangle() != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got.ToRectangle(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+//
+// trimoverride.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TrimOverride overrides trim options of pages in the page range
+type TrimOverride struct {
+	Pages    *PageRange
+	Enabled  *bool
+	Margin   *Sides[int]
+	MaxTrimP *Sides[float64]
+	MinSizeP *float64
+}
+
+// ParseTrimOverride parses "PAGES:key=value;key=value" (ex. "1-3,10:margin=0,0,20,0;max-trim=0.2").
+// The supported keys are trim, margin, max-trim and min-size.
+func ParseTrimOverride(str string, total int) (TrimOverride, error) {
+	var o TrimOverride
+	pages, options, ok := strings.Cut(str, ":")
+	if !ok {
+		return o, fmt.Errorf("'%s' must be in PAGES:key=value;key=value format", str)
+	}
+	o.Pages = NewPageRange()
+	if err := o.Pages.Parse(pages, total); err != nil {
+		return o, fmt.Errorf("parsing page range(%s): %w", pages, err)
+	}
+	for _, option := range strings.Split(options, ";") {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return o, fmt.Errorf("'%s' must be in key=value format", option)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "trim":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return o, fmt.Errorf("trim '%s' is not true or false: %w", value, err)
+			}
+			o.Enabled = &enabled
+		case "margin":
+			o.Margin = &Sides[int]{}
+			if err := o.Margin.Set(value); err != nil {
+				return o, fmt.Errorf("margin: %w", err)
+			}
+		case "max-trim":
+			o.MaxTrimP = &Sides[float64]{}
+			if err := o.MaxTrimP.Set(value); err != nil {
+				return o, fmt.Errorf("max-trim: %w", err)
+			}
+			if m := o.MaxTrimP; max(m.Top, m.Bottom, m.Inner, m.Outer) > 1.0 {
+				return o, fmt.Errorf("max-trim '%s' must be within [0.0-1.0]", value)
+			}
+		case "min-size":
+			var minSizeP float64
+			if _, err := fmt.Sscanln(value, &minSizeP); err != nil {
+				return o, fmt.Errorf("min-size '%s' is not a number: %w", value, err)
+			}
+			if minSizeP < 0.0 || minSizeP > 1.0 {
+				return o, fmt.Errorf("min-size '%s' must be within [0.0-1.0]", value)
+			}
+			o.MinSizeP = &minSizeP
+		default:
+			return o, fmt.Errorf("unknown trim option: %s", key)
+		}
+	}
+	return o, nil
+}
+
+// ForPage returns the trim config with overrides of the page applied in order
+func (cfg TrimConfig) ForPage(pageNo int) TrimConfig {
+	for _, o := range cfg.Overrides {
+		if !o.Pages.Contains(pageNo) {
+			continue
+		}
+		if o.Enabled != nil {
+			cfg.Enabled = *o.Enabled
+		}
+		if o.Margin != nil {
+			cfg.Margin = *o.Margin
+		}
+		if o.MaxTrimP != nil {
+			cfg.MaxTrimP = *o.MaxTrimP
+		}
+		if o.MinSizeP != nil {
+			cfg.MinSizeP = *o.MinSizeP
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,109 @@
+//
+// trimoverride_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"testing"
+)
+
+func TestSidesSet(t *testing.T) {
+	var margin Sides[int]
+	if err := margin.Set("5"); err != nil || margin != AllSides(5) {
+		t.Errorf("got %v (%v), want all 5", margin, err)
+	}
+	if err := margin.Set(" 1, 2,3 ,4"); err != nil || margin != (Sides[int]{Top: 1, Bottom: 2, Inner: 3, Outer: 4}) {
+		t.Errorf("got %v (%v), want 1,2,3,4", margin, err)
+	}
+	if got := margin.String(); got != "1,2,3,4" {
+		t.Errorf("got %s, want 1,2,3,4", got)
+	}
+
+	var maxTrim Sides[float64]
+	if err := maxTrim.Set("0.1,0.2,0,0.05"); err != nil || maxTrim != (Sides[float64]{0.1, 0.2, 0, 0.05}) {
+		t.Errorf("got %v (%v), want 0.1,0.2,0,0.05", maxTrim, err)
+	}
+	for _, val := range []string{"1,2", "1,2,3,4,5", "a", "1,2,x,4", "3x", "1.5", "-1", "1,2,-3,4"} {
+		if err := margin.Set(val); err == nil {
+			t.Errorf("Set(%q) should fail", val)
+		}
+	}
+}
+
+func TestSidesLeftRight(t *testing.T) {
+	s := Sides[int]{Top: 1, Bottom: 2, Inner: 3, Outer: 4}
+	newPage := func(pageNo int, isRTL bool, width int) *Page {
+		return newTestPage(newTestBook(isRTL), pageNo, width, 100)
+	}
+	tests := []struct {
+		page        *Page
+		left, right int
+	}{
+		{newPage(1, false, 60), 3, 4}, // LTR odd page is on the right
+		{newPage(2, false, 60), 4, 3},
+		{newPage(1, true, 60), 4, 3}, // RTL odd page is on the left
+		{newPage(2, true, 60), 3, 4},
+		{newPage(1, false, 160), 4, 4}, // landscape page has no inner side
+	}
+	for i, tt := range tests {
+		if left, right := s.LeftRight(tt.page); left != tt.left || right != tt.right {
+			t.Errorf("%d: got left=%d right=%d, want left=%d right=%d", i, left, right, tt.left, tt.right)
+		}
+	}
+}
+
+func TestParseTrimOverride(t *testing.T) {
+	o, err := ParseTrimOverride("1-2, 5: trim=false; margin=1,2,3,4; max-trim=0.2; min-size=0.5", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Pages.All(); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 5 {
+		t.Errorf("got pages %v, want [1 2 5]", got)
+	}
+	if o.Enabled == nil || *o.Enabled {
+		t.Errorf("got trim %v, want false", o.Enabled)
+	}
+	if o.Margin == nil || *o.Margin != (Sides[int]{1, 2, 3, 4}) {
+		t.Errorf("got margin %v, want 1,2,3,4", o.Margin)
+	}
+	if o.MaxTrimP == nil || *o.MaxTrimP != AllSides(0.2) {
+		t.Errorf("got max-trim %v, want 0.2", o.MaxTrimP)
+	}
+	if o.MinSizeP == nil || *o.MinSizeP != 0.5 {
+		t.Errorf("got min-size %v, want 0.5", o.MinSizeP)
+	}
+
+	for _, str := range []string{"1-2", "11:trim=false", "1:trim", "1:trim=maybe", "1:margin=1,2", "1:min-size=0.5x", "1:unknown=1",
+		"1:min-size=1.5", "1:min-size=-0.1", "1:max-trim=1.2", "1:max-trim=0.1,0.1,2,0", "1:max-trim=-0.1", "1:margin=-1"} {
+		if _, err := ParseTrimOverride(str, 10); err == nil {
+			t.Errorf("ParseTrimOverride(%q) should fail", str)
+		}
+	}
+}
+
+func TestTrimConfigForPage(t *testing.T) {
+	first, err := ParseTrimOverride("1-3:margin=8", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ParseTrimOverride("3:trim=false", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := TrimConfig{Enabled: true, Margin: AllSides(2), MinSizeP: 0.85, Overrides: []TrimOverride{first, second}}
+
+	if got := cfg.ForPage(5); !got.Enabled || got.Margin != AllSides(2) {
+		t.Errorf("page 5: got enabled=%t margin=%v, want the global config", got.Enabled, got.Margin)
+	}
+	if got := cfg.ForPage(1); !got.Enabled || got.Margin != AllSides(8) {
+		t.Errorf("page 1: got enabled=%t margin=%v, want margin 8", got.Enabled, got.Margin)
+	}
+	// later overrides are applied on top of earlier ones
+	if got := cfg.ForPage(3); got.Enabled || got.Margin != AllSides(8) || got.MinSizeP != 0.85 {
+		t.Errorf("page 3: got enabled=%t margin=%v min-size=%v, want disabled with margin 8", got.Enabled, got.Margin, got.MinSizeP)
+	}
+}
@@ -0,0 +1,212 @@
+//
+// uniformtrim.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+// UniformTrimMode is how the book-wide trim box is computed from trim boxes of all pages
+type UniformTrimMode int
+
+const (
+	UniformTrimNone   = iota // trim each page by its own trim box
+	UniformTrimUnion         // the smallest box containing trim boxes of all pages
+	UniformTrimMedian        // the median of each side of trim boxes of all pages
+)
+
+func (m UniformTrimMode) String() string {
+	switch m {
+	case UniformTrimNone:
+		return "none"
+	case UniformTrimUnion:
+		return "union"
+	case UniformTrimMedian:
+		return "median"
+	default:
+		return "unknown"
+	}
+}
+
+func (m *UniformTrimMode) Set(val string) error {
+	switch strings.ToLower(val) {
+	case "none":
+		*m = UniformTrimNone
+	case "union":
+		*m = UniformTrimUnion
+	case "median":
+		*m = UniformTrimMedian
+	default:
+		return fmt.Errorf("unknown uniform trim mode: %s", val)
+	}
+	return nil
+}
+
+// TrimSample is the trim box of a page measured in the first pass
+type TrimSample struct {
+	PageNo int
+	Size   Size // page size
+	Box    Rect // trim box. Empty if the page is blank
+}
+
+// insets is the distance of each side of trim box from the page edge
+type insets struct {
+	left, top, right, bottom int
+}
+
+func insetsOf(box Rect, size Size) insets {
+	return insets{
+		left:   box.MinX(),
+		top:    box.MinY(),
+		right:  int(size.Width) - box.MaxX(),
+		bottom: int(size.Height) - box.MaxY(),
+	}
+}
+
+func (in insets) String() string {
+	return fmt.Sprintf("left=%d top=%d right=%d bottom=%d", in.left, in.top, in.right, in.bottom)
+}
+
+func (in insets) rect(size Size) Rect {
+	frame := Rect{Point{}, size}
+	width := max(0, int(size.Width)-in.left-in.right)
+	height := max(0, int(size.Height)-in.top-in.bottom)
+	return Rect{Point{in.left, in.top}, Size{uint(width), uint(height)}}.BoundBy(frame)
+}
+
+// UniformTrim is the book-wide trim boxes. There are separate boxes for odd and even pages if parity is enabled.
+type UniformTrim struct {
+	parity   bool
+	union    bool
+	boxes    map[int]insets // by page group
+	outliers map[int]bool   // by page number
+}
+
+// NewUniformTrim computes the book-wide trim boxes from trim boxes of all pages.
+// A page is an outlier if any side of its trim box differs from the median by more than OutlierP of the page size.
+// Outlier and blank pages are excluded and the outlier pages use their own trim boxes.
+func NewUniformTrim(samples []TrimSample, cfg TrimConfig) *UniformTrim {
+	u := &UniformTrim{
+		parity:   cfg.UniformParity,
+		union:    cfg.Uniform == UniformTrimUnion,
+		boxes:    make(map[int]insets),
+		outliers: make(map[int]bool),
+	}
+
+	groups := make(map[int][]TrimSample)
+	for _, s := range samples {
+		if s.Box.size.Width == 0 || s.Box.size.Height == 0 {
+			continue // blank page
+		}
+		g := u.group(s.PageNo)
+		groups[g] = append(groups[g], s)
+	}
+
+	for g, group := range groups {
+		all := make([]insets, len(group))
+		for i, s := range group {
+			all[i] = insetsOf(s.Box, s.Size)
+		}
+		med := medianInsets(all)
+
+		box := insets{}
+		inliers := 0
+		for i, s := range group {
+			maxX := int(cfg.OutlierP * float64(s.Size.Width))
+			maxY := int(cfg.OutlierP * float64(s.Size.Height))
+			in := all[i]
+			if abs(in.left-med.left) > maxX || abs(in.right-med.right) > maxX ||
+				abs(in.top-med.top) > maxY || abs(in.bottom-med.bottom) > maxY {
+				u.outliers[s.PageNo] = true
+				continue
+			}
+			if inliers == 0 {
+				box = in
+			} else {
+				box = insets{min(box.left, in.left), min(box.top, in.top), min(box.right, in.right), min(box.bottom, in.bottom)}
+			}
+			inliers++
+		}
+		if !u.union {
+			box = med
+		}
+		u.boxes[g] = box
+	}
+	return u
+}
+
+func (u UniformTrim) group(pageNo int) int {
+	if u.parity {
+		return pageNo % 2
+	}
+	return 0
+}
+
+// Log prints the book-wide trim boxes
+func (u UniformTrim) Log() {
+	groups := make([]int, 0, len(u.boxes))
+	for g := range u.boxes {
+		groups = append(groups, g)
+	}
+	slices.Sort(groups)
+	for _, g := range groups {
+		name := "all"
+		if u.parity {
+			name = [2]string{"even", "odd"}[g]
+		}
+		log.Printf("[Trim] Book-wide trim insets of %s pages: %s", name, u.boxes[g])
+	}
+	if len(u.outliers) > 0 {
+		outliers := NewPageRange()
+		for pageNo := range u.outliers {
+			outliers.Add(pageNo, pageNo)
+		}
+		log.Printf("[Trim] Outlier pages using their own trim boxes: %s", outliers)
+	}
+}
+
+// Box returns the book-wide trim box for the page. Outlier and blank pages use their own trim boxes.
+// In median mode, content of an inlier page beyond the book-wide trim box is cut.
+func (u UniformTrim) Box(p *Page, own Rect) Rect {
+	in, ok := u.boxes[u.group(p.PageNo)]
+	if !ok || u.outliers[p.PageNo] || own.size.Width == 0 || own.size.Height == 0 {
+		p.log.Printf("[Trim] Page is an outlier or blank - using its own trim box")
+		return own
+	}
+	box := in.rect(p.Size())
+	p.log.Verbosef("[Trim] book-wide trim box: %s", box)
+	return box
+}
+
+func medianInsets(all []insets) insets {
+	median := func(value func(in insets) int) int {
+		values := make([]int, len(all))
+		for i, in := range all {
+			values[i] = value(in)
+		}
+		slices.Sort(values)
+		return values[len(values)/2]
+	}
+	return insets{
+		left:   median(func(in insets) int { return in.left }),
+		top:    median(func(in insets) int { return in.top }),
+		right:  median(func(in insets) int { return in.right }),
+		bottom: median(func(in insets) int { return in.bottom }),
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,75 @@
+//
+// uniformtrim_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package book
+
+import (
+	"testing"
+)
+
+var testPageSize = Size{Width: 600, Height: 800}
+
+// testSample returns the trim sample of a page with the insets of its trim box
+func testSample(pageNo int, left, top, right, bottom int) TrimSample {
+	return TrimSample{PageNo: pageNo, Size: testPageSize, Box: insets{left, top, right, bottom}.rect(testPageSize)}
+}
+
+func testTrimSamples() []TrimSample {
+	return []TrimSample{
+		testSample(1, 40, 50, 40, 50),
+		testSample(2, 30, 50, 44, 48),
+		testSample(3, 42, 56, 36, 50),
+		testSample(4, 40, 52, 40, 60),
+		testSample(5, 200, 300, 200, 300), // outlier
+		{PageNo: 6, Size: testPageSize},   // blank
+	}
+}
+
+func testTrimPage(pageNo int) *Page {
+	return newTestPage(newTestBook(false), pageNo, int(testPageSize.Width), int(testPageSize.Height))
+}
+
+func TestUniformTrimMedian(t *testing.T) {
+	u := NewUniformTrim(testTrimSamples(), TrimConfig{Uniform: UniformTrimMedian, OutlierP: 0.05})
+	if got, want := u.boxes[0], (insets{40, 52, 40, 50}); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if !u.outliers[5] || len(u.outliers) != 1 {
+		t.Errorf("got outliers %v, want page 5", u.outliers)
+	}
+
+	// the median box is not expanded to the page's own trim box
+	own := testSample(2, 30, 50, 44, 48).Box
+	if got, want := u.Box(testTrimPage(2), own), (insets{40, 52, 40, 50}).rect(testPageSize); got != want {
+		t.Errorf("page 2: got %s, want %s", got, want)
+	}
+	// outlier and blank pages use their own trim boxes
+	own = testSample(5, 200, 300, 200, 300).Box
+	if got := u.Box(testTrimPage(5), own); got != own {
+		t.Errorf("page 5: got %s, want %s", got, own)
+	}
+	if got := u.Box(testTrimPage(6), Rect{}); got != (Rect{}) {
+		t.Errorf("page 6: got %s, want empty", got)
+	}
+}
+
+func TestUniformTrimUnion(t *testing.T) {
+	u := NewUniformTrim(testTrimSamples(), TrimConfig{Uniform: UniformTrimUnion, OutlierP: 0.05})
+	if got, want := u.boxes[0], (insets{30, 50, 36, 48}); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestUniformTrimParity(t *testing.T) {
+	u := NewUniformTrim(testTrimSamples(), TrimConfig{Uniform: UniformTrimUnion, UniformParity: true, OutlierP: 0.05})
+	if got, want := u.boxes[1], (insets{40, 50, 36, 50}); got != want {
+		t.Errorf("odd pages: got %s, want %s", got, want)
+	}
+	if got, want := u.boxes[0], (insets{30, 50, 40, 48}); got != want {
+		t.Errorf("even pages: got %s, want %s", got, want)
+	}
+}
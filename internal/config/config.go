@@ -0,0 +1,162 @@
+//
+// config.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Profile is a set of command-line options without leading dashes (ex. "width": 1264)
+type Profile map[string]any
+
+// Config is the content of the config file.
+//
+//	{
+//	  "defaults": { "format": "kepub", "rtl": true },
+//	  "profiles": {
+//	    "my-kobo": { "width": 1264, "height": 1680, "background": ["#FFFFFF", "#000000"] }
+//	  }
+//	}
+type Config struct {
+	Defaults Profile            `json:"defaults"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// Built-in device profiles with screen resolutions in portrait orientation
+var builtinProfiles = map[string]Profile{
+	"kobo-clara-2e":      {"width": 1072, "height": 1448},
+	"kobo-libra2":        {"width": 1264, "height": 1680},
+	"kobo-sage":          {"width": 1440, "height": 1920},
+	"kobo-elipsa-2e":     {"width": 1404, "height": 1872},
+	"kindle-paperwhite5": {"width": 1236, "height": 1648},
+	"kindle-oasis":       {"width": 1264, "height": 1680},
+	"kindle-scribe":      {"width": 1860, "height": 2480},
+	"boox-note-air":      {"width": 1404, "height": 1872},
+	"boox-page":          {"width": 1264, "height": 1680},
+	"remarkable2":        {"width": 1404, "height": 1872},
+}
+
+func BuiltinProfileNames() []string {
+	return sortedKeys(builtinProfiles)
+}
+
+// DefaultPath returns the config file path in the user config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mangafmt", "config.json"), nil
+}
+
+// Load reads the config file.
+// It returns an empty config if the file does not exist and it is not required.
+func Load(path string, required bool) (*Config, error) {
+	var c Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return &c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Profile returns the profile by name. Profiles in the config file override the built-in profiles.
+func (c Config) Profile(name string) (Profile, error) {
+	if p, ok := c.Profiles[name]; ok {
+		return p, nil
+	}
+	if p, ok := builtinProfiles[name]; ok {
+		return p, nil
+	}
+	names := sortedKeys(c.Profiles)
+	for _, n := range BuiltinProfileNames() {
+		if !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return nil, fmt.Errorf("unknown profile '%s'. The available profiles are %s", name, strings.Join(names, ", "))
+}
+
+// Options returns the option values in string format sorted by option name.
+// Short option names are replaced by their long names in aliases.
+// A list value is joined by comma (ex. background colors) except for repeatable options
+// which have one entry per element (ex. trim-page overrides).
+func (p Profile) Options(aliases map[string]string, repeatable map[string]bool) ([][2]string, error) {
+	opts := make([][2]string, 0, len(p))
+	for _, name := range sortedKeys(p) {
+		value := p[name]
+		if long, ok := aliases[name]; ok {
+			name = long
+		}
+		if list, ok := value.([]any); ok && repeatable[name] {
+			for _, e := range list {
+				v, err := toString(e)
+				if err != nil {
+					return nil, fmt.Errorf("option %s: %w", name, err)
+				}
+				opts = append(opts, [2]string{name, v})
+			}
+			continue
+		}
+		v, err := toString(value)
+		if err != nil {
+			return nil, fmt.Errorf("option %s: %w", name, err)
+		}
+		opts = append(opts, [2]string{name, v})
+	}
+	return opts, nil
+}
+
+func toString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool, int, float64:
+		return fmt.Sprint(v), nil
+	case json.Number:
+		return v.String(), nil
+	case []any:
+		// list is joined by comma (ex. background colors)
+		parts := make([]string, 0, len(v))
+		for _, e := range v {
+			s, err := toString(e)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
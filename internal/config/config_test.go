@@ -0,0 +1,82 @@
+//
+// config_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfileOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"defaults": {"j": 4, "rtl": true, "fuzz": 0.2, "background": ["#FFFFFF", "#000000"], "trim-page": ["1-2:margin=0", "3:trim=false"]}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.Defaults.Options(map[string]string{"j": "jobs"}, map[string]bool{"trim-page": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{
+		{"background", "#FFFFFF,#000000"},
+		{"fuzz", "0.2"},
+		{"jobs", "4"},
+		{"rtl", "true"},
+		{"trim-page", "1-2:margin=0"},
+		{"trim-page", "3:trim=false"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Options() = %v, want %v", got, want)
+	}
+}
+
+func TestProfileOptionsUnsupportedValue(t *testing.T) {
+	p := Profile{"width": map[string]any{"a": json.Number("1")}}
+	if _, err := p.Options(nil, nil); err == nil {
+		t.Error("Options() with an object value must return an error")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "missing.json"), false)
+	if err != nil || c.Defaults != nil {
+		t.Errorf("Load() of missing optional file = %v, %v, want empty config", c, err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json"), true); err == nil {
+		t.Error("Load() of missing required file must return an error")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"unknown": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, true); err == nil {
+		t.Error("Load() with unknown field must return an error")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	c := Config{Profiles: map[string]Profile{"kobo-sage": {"width": 1}}}
+	if p, err := c.Profile("kobo-sage"); err != nil || p["width"] != 1 {
+		t.Errorf("Profile() = %v, %v, want the profile in the config file", p, err)
+	}
+	if p, err := c.Profile("kindle-scribe"); err != nil || p["width"] != 1860 {
+		t.Errorf("Profile() = %v, %v, want the built-in profile", p, err)
+	}
+	if _, err := c.Profile("unknown"); err == nil {
+		t.Error("Profile() of unknown name must return an error")
+	}
+}
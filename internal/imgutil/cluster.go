@@ -0,0 +1,133 @@
+//
+// cluster.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package imgutil
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+)
+
+// ColorCluster is a group of similar colors
+type ColorCluster struct {
+	Color  color.Color // mean color
+	Share  float64     // ratio of all samples [0.0-1.0]
+	Spread float64     // three times the root mean square distance of samples from the mean color [0.0-1.0]
+}
+
+type colorBin struct {
+	count   int
+	r, g, b float64 // sum of channels [0.0-1.0]
+	sq      float64 // sum of squares of all channels
+}
+
+func (b colorBin) mean() [3]float64 {
+	n := float64(b.count)
+	return [3]float64{b.r / n, b.g / n, b.b / n}
+}
+
+// ColorHistogram counts colors quantized to 5 bits per channel
+type ColorHistogram struct {
+	bins  map[uint16]*colorBin
+	total int
+}
+
+func NewColorHistogram() *ColorHistogram {
+	return &ColorHistogram{bins: make(map[uint16]*colorBin)}
+}
+
+// AddRect counts colors of all pixels in the rectangle
+func (h *ColorHistogram) AddRect(img image.Image, r image.Rectangle) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+			key := c.R>>11<<10 | c.G>>11<<5 | c.B>>11
+			bin, ok := h.bins[key]
+			if !ok {
+				bin = &colorBin{}
+				h.bins[key] = bin
+			}
+			bin.count++
+			bin.r += float64(c.R) / ColorRange
+			bin.g += float64(c.G) / ColorRange
+			bin.b += float64(c.B) / ColorRange
+			bin.sq += sqDiffF(float64(c.R)/ColorRange, 0) + sqDiffF(float64(c.G)/ColorRange, 0) + sqDiffF(float64(c.B)/ColorRange, 0)
+			h.total++
+		}
+	}
+}
+
+// Clusters groups colors within fuzz distance starting from the most frequent colors.
+// It returns clusters having at least minShare of all samples sorted by their shares.
+func (h *ColorHistogram) Clusters(fuzzP float64, minShare float64) []ColorCluster {
+	bins := make([]*colorBin, 0, len(h.bins))
+	for _, bin := range h.bins {
+		bins = append(bins, bin)
+	}
+	slices.SortFunc(bins, func(a, b *colorBin) int { return b.count - a.count })
+
+	type group struct {
+		seed [3]float64
+		sum  colorBin
+	}
+	groups := make([]*group, 0)
+	for _, bin := range bins {
+		mean := bin.mean()
+		var found *group
+		for _, g := range groups {
+			if colorDistance(g.seed, mean) <= fuzzP {
+				found = g
+				break
+			}
+		}
+		if found == nil {
+			found = &group{seed: mean}
+			groups = append(groups, found)
+		}
+		found.sum.count += bin.count
+		found.sum.r += bin.r
+		found.sum.g += bin.g
+		found.sum.b += bin.b
+		found.sum.sq += bin.sq
+	}
+
+	clusters := make([]ColorCluster, 0)
+	for _, g := range groups {
+		share := float64(g.sum.count) / float64(h.total)
+		if share < minShare {
+			continue
+		}
+		mean := g.sum.mean()
+
+		// mean squared distance = mean of squares - square of mean (per channel)
+		n := float64(g.sum.count)
+		variance := g.sum.sq/n - (mean[0]*mean[0] + mean[1]*mean[1] + mean[2]*mean[2])
+		spread := 3 * math.Sqrt(max(0, variance)/3)
+
+		clusters = append(clusters, ColorCluster{
+			Color: color.NRGBA64{
+				R: uint16(math.Round(mean[0] * ColorRange)),
+				G: uint16(math.Round(mean[1] * ColorRange)),
+				B: uint16(math.Round(mean[2] * ColorRange)),
+				A: ColorRange,
+			},
+			Share:  share,
+			Spread: spread,
+		})
+	}
+	slices.SortStableFunc(clusters, func(a, b ColorCluster) int { return cmp.Compare(b.Share, a.Share) })
+	return clusters
+}
+
+// colorDistance is the root mean square of channel differences like IsColorSimilar()
+func colorDistance(u [3]float64, v [3]float64) float64 {
+	return math.Sqrt((sqDiffF(u[0], v[0]) + sqDiffF(u[1], v[1]) + sqDiffF(u[2], v[2])) / 3)
+}
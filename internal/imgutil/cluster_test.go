@@ -0,0 +1,48 @@
+//
+// cluster_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package imgutil
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestColorHistogramClusters(t *testing.T) {
+	// 100 pixels: 40 paper + 30 slightly darker paper, 25 black and 5 red
+	img := image.NewRGBA(image.Rect(0, 0, 100, 1))
+	draw.Draw(img, image.Rect(0, 0, 40, 1), image.NewUniform(color.RGBA{0xf0, 0xe6, 0xc8, 0xff}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(40, 0, 70, 1), image.NewUniform(color.RGBA{0xe8, 0xde, 0xc0, 0xff}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(70, 0, 95, 1), image.Black, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(95, 0, 100, 1), image.NewUniform(color.RGBA{0xff, 0, 0, 0xff}), image.Point{}, draw.Src)
+
+	h := NewColorHistogram()
+	h.AddRect(img, img.Bounds())
+	clusters := h.Clusters(0.1, 0.1)
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2: %v", len(clusters), clusters)
+	}
+
+	paper := clusters[0]
+	if math.Abs(paper.Share-0.7) > 1e-9 {
+		t.Errorf("got paper share %f, want 0.7", paper.Share)
+	}
+	if got := ToHexString(paper.Color); got != "#ede3c5" {
+		t.Errorf("got paper color %s, want #ede3c5", got)
+	}
+	if paper.Spread <= 0 || paper.Spread > 0.1 {
+		t.Errorf("got paper spread %f, want within (0, 0.1]", paper.Spread)
+	}
+
+	ink := clusters[1]
+	if math.Abs(ink.Share-0.25) > 1e-9 || ToHexString(ink.Color) != "#000000" || ink.Spread > 1e-6 {
+		t.Errorf("got ink %s share=%f spread=%f, want #000000 share=0.25 spread=0", ToHexString(ink.Color), ink.Share, ink.Spread)
+	}
+}
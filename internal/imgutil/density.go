@@ -0,0 +1,219 @@
+//
+// density.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package imgutil
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// DensityTrimOptions are the options to find the trim box by ink density
+type DensityTrimOptions struct {
+	MinComponent int     // connected components with fewer pixels outside dense regions are ignored (ex. dust specks)
+	MinDensity   float64 // minimum ratio of ink pixels in a row or column to be content [0.0-1.0]
+	HeaderBand   int     // components entirely within this top band are ignored (pixel)
+	FooterBand   int     // components entirely within this bottom band are ignored (ex. page numbers) (pixel)
+	MaxEdgeLine  int     // thin lines near the page edges up to this thickness are ignored (ex. scanner edges) (pixel)
+}
+
+// Thin lines are ignored if they are within edgeZone (ratio of the page size) from an edge
+// and at least minEdgeLine (ratio of the page size) long (ex. scanner edges but not letters like 'l' or '1')
+const (
+	edgeZone    = 0.05
+	minEdgeLine = 0.25
+)
+
+// A small component is kept if the other ink around it within speckRadius (ratio of the page size)
+// covers at least denseRegion of the area (ex. screentone dots)
+const (
+	speckRadius = 0.01
+	denseRegion = 0.05
+)
+
+type component struct {
+	size   int
+	bounds image.Rectangle
+}
+
+// Label of ink pixels which are not labeled yet
+const unlabeled = -1
+
+// densityBuffers are per-pixel buffers which are reused across pages
+type densityBuffers struct {
+	labels []int32 // 0 is background, unlabeled is ink and label i is components[i-1]
+	sums   []int32 // summed-area table of ink pixels
+	stack  []int
+}
+
+var densityPool = sync.Pool{New: func() any { return new(densityBuffers) }}
+
+// zeroed returns the slice with length n filled with zero. Its array is reused if the capacity is enough.
+func zeroed(s []int32, n int) []int32 {
+	if cap(s) < n {
+		return make([]int32, n)
+	}
+	s = s[:n]
+	clear(s)
+	return s
+}
+
+// TrimRectByDensity returns the bounding box of the content by ink density per row and column.
+// A pixel is ink if it is not similar to any of background colors.
+// Small connected components outside dense regions, components in the header/footer band and thin lines near the edges are ignored.
+func TrimRectByDensity(img image.Image, bgColors []color.Color, fuzzP float64, opts DensityTrimOptions) image.Rectangle {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width == 0 || height == 0 {
+		return image.Rectangle{}
+	}
+
+	buf := densityPool.Get().(*densityBuffers)
+	defer densityPool.Put(buf)
+	buf.labels = zeroed(buf.labels, width*height)
+	labels := buf.labels
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := img.At(x+b.Min.X, y+b.Min.Y)
+			isBg := false
+			for _, bg := range bgColors {
+				if IsColorSimilar(c, bg, fuzzP) {
+					isBg = true
+					break
+				}
+			}
+			if !isBg {
+				labels[y*width+x] = unlabeled
+			}
+		}
+	}
+
+	components := labelComponents(buf, width, height)
+	sums := integralImage(buf, width, height)
+	radius := max(2, int(speckRadius*float64(min(width, height))))
+	zoneX, zoneY := int(edgeZone*float64(width)), int(edgeZone*float64(height))
+	ignored := make([]bool, len(components))
+	for i, c := range components {
+		r := c.bounds
+		switch {
+		case c.size < opts.MinComponent:
+			// speck unless it is among other ink
+			around := r.Inset(-radius).Intersect(image.Rect(0, 0, width, height))
+			others := sums.count(around) - c.size
+			ignored[i] = float64(others) < denseRegion*float64(around.Dx()*around.Dy()-c.size)
+		case opts.HeaderBand > 0 && r.Max.Y <= opts.HeaderBand:
+			ignored[i] = true
+		case opts.FooterBand > 0 && r.Min.Y >= height-opts.FooterBand:
+			ignored[i] = true
+		case r.Dx() <= opts.MaxEdgeLine && r.Dy() >= int(minEdgeLine*float64(height)) && (r.Min.X < zoneX || r.Max.X > width-zoneX):
+			// vertical line near left or right edge
+			ignored[i] = true
+		case r.Dy() <= opts.MaxEdgeLine && r.Dx() >= int(minEdgeLine*float64(width)) && (r.Min.Y < zoneY || r.Max.Y > height-zoneY):
+			// horizontal line near top or bottom edge
+			ignored[i] = true
+		}
+	}
+
+	rows := make([]int, height)
+	cols := make([]int, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if l := labels[y*width+x]; l > 0 && !ignored[l-1] {
+				rows[y]++
+				cols[x]++
+			}
+		}
+	}
+
+	top, bottom := contentRange(rows, max(1, int(math.Ceil(opts.MinDensity*float64(width)))))
+	left, right := contentRange(cols, max(1, int(math.Ceil(opts.MinDensity*float64(height)))))
+	if top >= bottom || left >= right {
+		// blank page
+		return image.Rectangle{}
+	}
+	return image.Rect(left, top, right, bottom).Add(b.Min)
+}
+
+// labelComponents labels 8-connected ink pixels in buf.labels. Label i is components[i-1].
+func labelComponents(buf *densityBuffers, width int, height int) []component {
+	labels := buf.labels
+	components := make([]component, 0)
+	stack := buf.stack[:0]
+	for start, l := range labels {
+		if l != unlabeled {
+			continue
+		}
+		components = append(components, component{})
+		label := int32(len(components))
+		c := &components[label-1]
+		c.bounds = image.Rect(start%width, start/width, start%width+1, start/width+1)
+
+		labels[start] = label
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%width, i/width
+			c.size++
+			c.bounds = c.bounds.Union(image.Rect(x, y, x+1, y+1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || nx >= width || ny < 0 || ny >= height {
+						continue
+					}
+					if j := ny*width + nx; labels[j] == unlabeled {
+						labels[j] = label
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+	}
+	buf.stack = stack
+	return components
+}
+
+// integral is the summed-area table of ink pixels
+type integral struct {
+	width int
+	sums  []int32
+}
+
+func integralImage(buf *densityBuffers, width int, height int) integral {
+	buf.sums = zeroed(buf.sums, (width+1)*(height+1))
+	t := integral{width: width + 1, sums: buf.sums}
+	for y := 0; y < height; y++ {
+		row := int32(0)
+		for x := 0; x < width; x++ {
+			if buf.labels[y*width+x] != 0 {
+				row++
+			}
+			t.sums[(y+1)*t.width+x+1] = t.sums[y*t.width+x+1] + row
+		}
+	}
+	return t
+}
+
+// count returns the number of ink pixels in the rectangle
+func (t integral) count(r image.Rectangle) int {
+	return int(t.sums[r.Max.Y*t.width+r.Max.X] - t.sums[r.Min.Y*t.width+r.Max.X] - t.sums[r.Max.Y*t.width+r.Min.X] + t.sums[r.Min.Y*t.width+r.Min.X])
+}
+
+// contentRange returns the range [start, end) from the first to the last count which is at least minCount
+func contentRange(counts []int, minCount int) (int, int) {
+	start, end := 0, len(counts)
+	for start < end && counts[start] < minCount {
+		start++
+	}
+	for end > start && counts[end-1] < minCount {
+		end--
+	}
+	return start, end
+}
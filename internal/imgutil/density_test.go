@@ -0,0 +1,98 @@
+//
+// density_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package imgutil
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var densityOpts = DensityTrimOptions{MinComponent: 25}
+
+func testDensityPage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 400, 600))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(100, 100, 300, 500), image.Black, image.Point{}, draw.Src)
+	return img
+}
+
+func TestTrimRectByDensityIgnoresSpeck(t *testing.T) {
+	img := testDensityPage()
+	draw.Draw(img, image.Rect(380, 20, 383, 23), image.Black, image.Point{}, draw.Src)
+
+	got := TrimRectByDensity(img, []color.Color{color.White}, 0.1, densityOpts)
+	if want := image.Rect(100, 100, 300, 500); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTrimRectByDensityKeepsScreentone(t *testing.T) {
+	img := testDensityPage()
+	// 2x2 dots every 5 pixels
+	for y := 520; y < 580; y += 5 {
+		for x := 20; x < 100; x += 5 {
+			draw.Draw(img, image.Rect(x, y, x+2, y+2), image.Black, image.Point{}, draw.Src)
+		}
+	}
+
+	got := TrimRectByDensity(img, []color.Color{color.White}, 0.1, densityOpts)
+	if want := image.Rect(20, 100, 300, 577); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTrimRectByDensityBlankPage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 40, 60))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	if got := TrimRectByDensity(img, []color.Color{color.White}, 0.1, densityOpts); !got.Empty() {
+		t.Errorf("got %v, want empty", got)
+	}
+}
+
+func TestTrimRectByDensityBandsAndEdgeLines(t *testing.T) {
+	content := image.Rect(100, 100, 300, 500)
+	tests := []struct {
+		name string
+		ink  []image.Rectangle
+		opts DensityTrimOptions
+		want image.Rectangle
+	}{
+		{"footer page number", []image.Rectangle{image.Rect(190, 570, 210, 585)},
+			DensityTrimOptions{MinComponent: 25, FooterBand: 40}, content},
+		{"page number without footer band", []image.Rectangle{image.Rect(190, 570, 210, 585)},
+			DensityTrimOptions{MinComponent: 25}, image.Rect(100, 100, 300, 585)},
+		{"header title", []image.Rectangle{image.Rect(190, 10, 210, 25)},
+			DensityTrimOptions{MinComponent: 25, HeaderBand: 40}, content},
+		{"content across footer band", []image.Rectangle{image.Rect(350, 540, 360, 590)},
+			DensityTrimOptions{MinComponent: 25, FooterBand: 40}, image.Rect(100, 100, 360, 590)},
+		{"vertical scanner edge", []image.Rectangle{image.Rect(5, 0, 7, 600)},
+			DensityTrimOptions{MinComponent: 25, MaxEdgeLine: 3}, content},
+		{"horizontal scanner edge", []image.Rectangle{image.Rect(0, 595, 400, 597)},
+			DensityTrimOptions{MinComponent: 25, MaxEdgeLine: 3}, content},
+		{"short thin letter near edge", []image.Rectangle{image.Rect(10, 300, 12, 330)},
+			DensityTrimOptions{MinComponent: 25, MaxEdgeLine: 3}, image.Rect(10, 100, 300, 500)},
+		{"short thin rule near edge", []image.Rectangle{image.Rect(150, 590, 180, 592)},
+			DensityTrimOptions{MinComponent: 25, MaxEdgeLine: 3}, image.Rect(100, 100, 300, 592)},
+		{"thick line near edge", []image.Rectangle{image.Rect(5, 0, 15, 600)},
+			DensityTrimOptions{MinComponent: 25, MaxEdgeLine: 3}, image.Rect(5, 0, 300, 600)},
+		{"long thin line away from edges", []image.Rectangle{image.Rect(350, 100, 352, 500)},
+			DensityTrimOptions{MinComponent: 25, MaxEdgeLine: 3}, image.Rect(100, 100, 352, 500)},
+	}
+	for _, tt := range tests {
+		img := testDensityPage()
+		for _, r := range tt.ink {
+			draw.Draw(img, r, image.Black, image.Point{}, draw.Src)
+		}
+		if got := TrimRectByDensity(img, []color.Color{color.White}, 0.1, tt.opts); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ package imgutil
 
 import (
 	"image"
+	"image/color"
 	"math"
 )
 
@@ -28,3 +29,53 @@ func GetRMSEDistortion(img1 image.Image, r image.Rectangle, img2 image.Image, sp
 	distortion = distortion / float64(bounds.Dx()*bounds.Dy())
 	return math.Sqrt(distortion)
 }
+
+// RowProfile returns the mean luminance [0.0-1.0] of each row in the rectangle
+func RowProfile(img image.Image, r image.Rectangle) []float64 {
+	profile := make([]float64, r.Dy())
+	if r.Dx() == 0 {
+		return profile
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		sum := 0.0
+		for x := r.Min.X; x < r.Max.X; x++ {
+			gray := color.Gray16Model.Convert(img.At(x, y)).(color.Gray16)
+			sum += float64(gray.Y) / ColorRange
+		}
+		profile[y-r.Min.Y] = sum / float64(r.Dx())
+	}
+	return profile
+}
+
+// GradientCorrelation returns the normalized cross-correlation [-1.0-1.0] of the gradients of two profiles.
+// It returns 0 if either profile is almost flat because there is nothing to correlate.
+func GradientCorrelation(p1 []float64, p2 []float64) float64 {
+	n := min(len(p1), len(p2)) - 1
+	if n < 2 {
+		return 0
+	}
+	g1 := make([]float64, n)
+	g2 := make([]float64, n)
+	mean1, mean2 := 0.0, 0.0
+	for i := 0; i < n; i++ {
+		g1[i] = p1[i+1] - p1[i]
+		g2[i] = p2[i+1] - p2[i]
+		mean1 += g1[i]
+		mean2 += g2[i]
+	}
+	mean1 /= float64(n)
+	mean2 /= float64(n)
+
+	cov, var1, var2 := 0.0, 0.0, 0.0
+	for i := 0; i < n; i++ {
+		d1, d2 := g1[i]-mean1, g2[i]-mean2
+		cov += d1 * d2
+		var1 += d1 * d1
+		var2 += d2 * d2
+	}
+	const minStdDev = 1.0 / 255 // less than one 8-bit level
+	if var1/float64(n) < minStdDev*minStdDev || var2/float64(n) < minStdDev*minStdDev {
+		return 0
+	}
+	return cov / math.Sqrt(var1*var2)
+}
@@ -74,6 +74,47 @@ func ColorDepth(src image.Image) uint {
 	}
 }
 
+// IsGrayscale checks if all pixels of the image are gray (R=G=B)
+func IsGrayscale(img image.Image) bool {
+	b := img.Bounds()
+	switch v := img.(type) {
+	case *image.Gray, *image.Gray16:
+		return true
+	case *image.YCbCr:
+		// gray if there is no chroma
+		for _, c := range v.Cb {
+			if c != 128 {
+				return false
+			}
+		}
+		for _, c := range v.Cr {
+			if c != 128 {
+				return false
+			}
+		}
+		return true
+	case *image.RGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := v.Pix[v.PixOffset(x, y):]
+				if p[0] != p[1] || p[1] != p[2] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			if r != g || g != bl {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func TransformToGrayColorModel(img image.Image) image.Image {
 	switch img.(type) {
 	case *image.Gray16, *image.Gray:
@@ -100,11 +141,7 @@ func QuantizeAndDither(img image.Image, numColor int) *image.Paletted {
 }
 
 func Resize(src image.Image, size image.Point) image.Image {
-	canvas := NewCanvasSameColor(src, image.Rect(0, 0, size.X, size.Y))
-
-	// Resize
-	drawx.CatmullRom.Scale(canvas, canvas.Bounds(), src, src.Bounds(), draw.Src, nil)
-	return canvas
+	return ResizeWith(src, size, drawx.CatmullRom)
 }
 
 func Rotate(src image.Image, degree float64) image.Image {
@@ -116,8 +153,8 @@ func Rotate(src image.Image, degree float64) image.Image {
 		math.Sin(rad), math.Cos(rad), 0,
 	}
 	size := src.Bounds().Size()
-	width := int((mm[0] * float64(size.X)) + (mm[1] * float64(size.Y)))
-	height := int((mm[3] * float64(size.X)) + (mm[4] * float64(size.Y)))
+	width := int(math.Round((mm[0] * float64(size.X)) + (mm[1] * float64(size.Y))))
+	height := int(math.Round((mm[3] * float64(size.X)) + (mm[4] * float64(size.Y))))
 
 	canvas := NewCanvasSameColor(src, image.Rect(0, 0, width, height))
 
@@ -144,24 +181,55 @@ func CropImage(src image.Image, rect image.Rectangle) image.Image {
 	return dst
 }
 
-func AppendHorizontally(img1 image.Image, img2 image.Image) image.Image {
+// Letterbox draws the image at the center of the canvas of the size filled with background color
+func Letterbox(src image.Image, size image.Point, bgColor color.Color) image.Image {
+	canvas := NewCanvasSameColor(src, image.Rectangle{Max: size})
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(bgColor), image.Point{}, draw.Src)
+	offset := size.Sub(src.Bounds().Size()).Div(2)
+	draw.Draw(canvas, image.Rectangle{Min: offset, Max: offset.Add(src.Bounds().Size())}, src, src.Bounds().Min, draw.Src)
+	return canvas
+}
+
+// AppendHorizontally draws img2 on the right of img1 shifted vertically by offsetY.
+// The last `overlap` columns of img1 and the first columns of img2 are the same artwork so they are blended into one.
+// The uncovered area is filled with background color.
+func AppendHorizontally(img1 image.Image, img2 image.Image, offsetY int, overlap int, bgColor color.Color) image.Image {
 	r1 := img1.Bounds().Size()
 	r2 := img2.Bounds().Size()
+	overlap = max(0, min(overlap, r1.X, r2.X))
 
+	top := min(0, offsetY)
+	bottom := max(r1.Y, offsetY+r2.Y)
 	r := image.Rectangle{
 		Min: image.Pt(0, 0),
-		Max: image.Pt(r1.X+r2.X, max(r1.Y, r2.Y)),
+		Max: image.Pt(r1.X+r2.X-overlap, bottom-top),
 	}
 
 	canvas := NewCanvasSameColor(img1, r)
+	draw.Draw(canvas, r, image.NewUniform(bgColor), image.Point{}, draw.Src)
 	draw.Draw(canvas, image.Rectangle{
-		Min: r.Min,
-		Max: r1,
+		Min: image.Pt(0, -top),
+		Max: r1.Add(image.Pt(0, -top)),
 	}, img1, img1.Bounds().Min, draw.Src)
+	x2 := r1.X - overlap
 	draw.Draw(canvas, image.Rectangle{
-		Min: r.Min.Add(image.Pt(r1.X, 0)),
-		Max: r2.Add(image.Pt(r1.X, 0)),
+		Min: image.Pt(x2, offsetY-top),
+		Max: r2.Add(image.Pt(x2, offsetY-top)),
 	}, img2, img2.Bounds().Min, draw.Src)
+
+	// Blend the overlap linearly from img1 to img2
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	for x := 0; x < overlap; x++ {
+		w := (float64(x) + 0.5) / float64(overlap)
+		for y := max(0, offsetY); y < min(r1.Y, offsetY+r2.Y); y++ {
+			c1 := color.NRGBA64Model.Convert(img1.At(b1.Min.X+x2+x, b1.Min.Y+y)).(color.NRGBA64)
+			c2 := color.NRGBA64Model.Convert(img2.At(b2.Min.X+x, b2.Min.Y+y-offsetY)).(color.NRGBA64)
+			lerp := func(v1 uint16, v2 uint16) uint16 {
+				return uint16(math.Round(float64(v1)*(1-w) + float64(v2)*w))
+			}
+			canvas.Set(x2+x, y-top, color.NRGBA64{lerp(c1.R, c2.R), lerp(c1.G, c2.G), lerp(c1.B, c2.B), lerp(c1.A, c2.A)})
+		}
+	}
 	return canvas
 }
 
@@ -235,3 +303,111 @@ rightSearch:
 	trimRect := image.Rect(left, top, right+1, bottom+1)
 	return trimRect, nil
 }
+
+// TrimRectBySide returns the bounding box of the content like TrimRect but each side is trimmed against its own background color.
+// The background colors are in the order of top, left, bottom and right.
+// The sides are trimmed repeatedly within the other sides so that a border of one side does not block trimming the adjacent sides.
+func TrimRectBySide(img image.Image, bgColors [4]color.Color, fuzzP float64) image.Rectangle {
+	r := img.Bounds()
+	rowIsBg := func(y int, side int) bool {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if !IsColorSimilar(img.At(x, y), bgColors[side], fuzzP) {
+				return false
+			}
+		}
+		return true
+	}
+	colIsBg := func(x int, side int) bool {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			if !IsColorSimilar(img.At(x, y), bgColors[side], fuzzP) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		prev := r
+		for r.Min.Y < r.Max.Y && rowIsBg(r.Min.Y, 0) {
+			r.Min.Y++
+		}
+		for r.Max.Y > r.Min.Y && rowIsBg(r.Max.Y-1, 2) {
+			r.Max.Y--
+		}
+		for r.Min.X < r.Max.X && colIsBg(r.Min.X, 1) {
+			r.Min.X++
+		}
+		for r.Max.X > r.Min.X && colIsBg(r.Max.X-1, 3) {
+			r.Max.X--
+		}
+		if r.Empty() {
+			// blank page
+			return image.Rectangle{}
+		}
+		if r == prev {
+			return r
+		}
+	}
+}
+
+// BackgroundRatios returns the ratio of pixels in the rectangle which are similar to each of background colors
+func BackgroundRatios(img image.Image, r image.Rectangle, bgColors []color.Color, fuzzP float64) []float64 {
+	ratios := make([]float64, len(bgColors))
+	if r.Empty() {
+		return ratios
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.At(x, y)
+			for i, bg := range bgColors {
+				if IsColorSimilar(c, bg, fuzzP) {
+					ratios[i]++
+				}
+			}
+		}
+	}
+	for i := range ratios {
+		ratios[i] /= float64(r.Dx() * r.Dy())
+	}
+	return ratios
+}
+
+// ForegroundRatio returns the ratio of pixels which are not similar to the background color
+func ForegroundRatio(img image.Image, bgColor color.Color, fuzzP float64) float64 {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+	count := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !IsColorSimilar(img.At(x, y), bgColor, fuzzP) {
+				count++
+			}
+		}
+	}
+	return float64(count) / float64(bounds.Dx()*bounds.Dy())
+}
+
+// ColumnBackgroundRatios returns the ratio of background pixels in each column of the rectangle.
+// A pixel is background if it is similar to any of background colors.
+func ColumnBackgroundRatios(img image.Image, r image.Rectangle, bgColors []color.Color, fuzzP float64) []float64 {
+	ratios := make([]float64, r.Dx())
+	if r.Dy() == 0 {
+		return ratios
+	}
+	for x := r.Min.X; x < r.Max.X; x++ {
+		count := 0
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			c := img.At(x, y)
+			for _, bg := range bgColors {
+				if IsColorSimilar(c, bg, fuzzP) {
+					count++
+					break
+				}
+			}
+		}
+		ratios[x-r.Min.X] = float64(count) / float64(r.Dy())
+	}
+	return ratios
+}
@@ -0,0 +1,187 @@
+//
+// pnm.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package imgutil
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Binary PGM(P5) and PPM(P6) image decoder
+func init() {
+	image.RegisterFormat("pnm", "P5", DecodePNM, DecodePNMConfig)
+	image.RegisterFormat("pnm", "P6", DecodePNM, DecodePNMConfig)
+}
+
+type pnmHeader struct {
+	channels int
+	width    int
+	height   int
+	maxVal   int
+}
+
+func readPNMHeader(r *bufio.Reader) (pnmHeader, error) {
+	var h pnmHeader
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return h, err
+	}
+	switch string(magic) {
+	case "P5":
+		h.channels = 1
+	case "P6":
+		h.channels = 3
+	default:
+		return h, fmt.Errorf("pnm: unsupported magic %q", magic)
+	}
+
+	values := []*int{&h.width, &h.height, &h.maxVal}
+	for _, v := range values {
+		n, err := readPNMInt(r)
+		if err != nil {
+			return h, fmt.Errorf("pnm: reading header: %w", err)
+		}
+		*v = n
+	}
+	if h.width <= 0 || h.height <= 0 || h.maxVal <= 0 || h.maxVal > 0xffff {
+		return h, fmt.Errorf("pnm: invalid header %dx%d maxval=%d", h.width, h.height, h.maxVal)
+	}
+	return h, nil
+}
+
+// readPNMInt reads an integer after skipping whitespaces and comments.
+// It also consumes a single whitespace after the integer.
+func readPNMInt(r *bufio.Reader) (int, error) {
+	n := 0
+	digits := 0
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case c == '#' && digits == 0:
+			if _, err := r.ReadString('\n'); err != nil {
+				return 0, err
+			}
+		case '0' <= c && c <= '9':
+			n = n*10 + int(c-'0')
+			digits++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if digits > 0 {
+				return n, nil
+			}
+		default:
+			return 0, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+}
+
+func DecodePNMConfig(r io.Reader) (image.Config, error) {
+	h, err := readPNMHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	var model color.Model
+	switch {
+	case h.channels == 1 && h.maxVal < 256:
+		model = color.GrayModel
+	case h.channels == 1:
+		model = color.Gray16Model
+	case h.maxVal < 256:
+		model = color.RGBAModel
+	default:
+		model = color.RGBA64Model
+	}
+	return image.Config{ColorModel: model, Width: h.width, Height: h.height}, nil
+}
+
+func DecodePNM(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readPNMHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	bytesPerSample := 1
+	if h.maxVal >= 256 {
+		bytesPerSample = 2
+	}
+	row := make([]byte, h.width*h.channels*bytesPerSample)
+	rect := image.Rect(0, 0, h.width, h.height)
+	scale := func(v int) int {
+		if h.maxVal == 0xff || h.maxVal == 0xffff {
+			return v
+		}
+		// scale to full range. Samples above maxval are clamped.
+		v = min(v, h.maxVal)
+		if bytesPerSample == 1 {
+			return v * 0xff / h.maxVal
+		}
+		return v * 0xffff / h.maxVal
+	}
+	sample := func(i int) int {
+		if bytesPerSample == 1 {
+			return scale(int(row[i]))
+		}
+		return scale(int(row[2*i])<<8 | int(row[2*i+1]))
+	}
+
+	var img image.Image
+	switch {
+	case h.channels == 1 && bytesPerSample == 1:
+		dst := image.NewGray(rect)
+		for y := 0; y < h.height; y++ {
+			if _, err := io.ReadFull(br, row); err != nil {
+				return nil, fmt.Errorf("pnm: reading row %d: %w", y, err)
+			}
+			for x := 0; x < h.width; x++ {
+				dst.Pix[y*dst.Stride+x] = uint8(sample(x))
+			}
+		}
+		img = dst
+	case h.channels == 1:
+		dst := image.NewGray16(rect)
+		for y := 0; y < h.height; y++ {
+			if _, err := io.ReadFull(br, row); err != nil {
+				return nil, fmt.Errorf("pnm: reading row %d: %w", y, err)
+			}
+			for x := 0; x < h.width; x++ {
+				dst.SetGray16(x, y, color.Gray16{Y: uint16(sample(x))})
+			}
+		}
+		img = dst
+	case bytesPerSample == 1:
+		dst := image.NewRGBA(rect)
+		for y := 0; y < h.height; y++ {
+			if _, err := io.ReadFull(br, row); err != nil {
+				return nil, fmt.Errorf("pnm: reading row %d: %w", y, err)
+			}
+			for x := 0; x < h.width; x++ {
+				p := dst.Pix[y*dst.Stride+x*4:]
+				p[0], p[1], p[2], p[3] = uint8(sample(3*x)), uint8(sample(3*x+1)), uint8(sample(3*x+2)), 0xff
+			}
+		}
+		img = dst
+	default:
+		dst := image.NewRGBA64(rect)
+		for y := 0; y < h.height; y++ {
+			if _, err := io.ReadFull(br, row); err != nil {
+				return nil, fmt.Errorf("pnm: reading row %d: %w", y, err)
+			}
+			for x := 0; x < h.width; x++ {
+				dst.SetRGBA64(x, y, color.RGBA64{R: uint16(sample(3 * x)), G: uint16(sample(3*x + 1)), B: uint16(sample(3*x + 2)), A: 0xffff})
+			}
+		}
+		img = dst
+	}
+	return img, nil
+}
@@ -0,0 +1,104 @@
+//
+// pnm_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package imgutil
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDecodePNM(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		model color.Model
+		width int
+		want  []color.Color // pixels in row-major order
+	}{
+		{"gray", "P5\n3 1\n255\n\x00\x80\xff", color.GrayModel, 3,
+			[]color.Color{color.Gray{0x00}, color.Gray{0x80}, color.Gray{0xff}}},
+		{"comments in header", "P5\n# created by test\n2 1 # size\n# maxval\n255\n\x10\x20", color.GrayModel, 2,
+			[]color.Color{color.Gray{0x10}, color.Gray{0x20}}},
+		{"whitespaces in header", "P5 \t2\r\n\n 1\t255 \x10\x20", color.GrayModel, 2,
+			[]color.Color{color.Gray{0x10}, color.Gray{0x20}}},
+		{"gray maxval 15", "P5\n3 1\n15\n\x00\x05\x0f", color.GrayModel, 3,
+			[]color.Color{color.Gray{0}, color.Gray{85}, color.Gray{255}}},
+		{"gray sample above maxval", "P5\n1 1\n100\n\xc8", color.GrayModel, 1,
+			[]color.Color{color.Gray{255}}},
+		{"16-bit gray", "P5\n2 1\n65535\n\x12\x34\xff\xff", color.Gray16Model, 2,
+			[]color.Color{color.Gray16{0x1234}, color.Gray16{0xffff}}},
+		{"16-bit gray maxval 1023", "P5\n2 1\n1023\n\x03\xff\x02\x00", color.Gray16Model, 2,
+			[]color.Color{color.Gray16{0xffff}, color.Gray16{32799}}},
+		{"rgb", "P6\n2 2\n255\n\xff\x00\x00\x00\xff\x00\x00\x00\xff\x10\x20\x30", color.RGBAModel, 2,
+			[]color.Color{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0xff, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}, color.RGBA{0x10, 0x20, 0x30, 0xff}}},
+		{"16-bit rgb", "P6\n1 1\n65535\n\x12\x34\x56\x78\x9a\xbc", color.RGBA64Model, 1,
+			[]color.Color{color.RGBA64{0x1234, 0x5678, 0x9abc, 0xffff}}},
+	}
+	for _, tt := range tests {
+		img, err := DecodePNM(bytes.NewReader([]byte(tt.data)))
+		if err != nil {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if img.ColorModel() != tt.model {
+			t.Errorf("%s: got color model %T, want %T", tt.name, img.ColorModel(), tt.model)
+		}
+		if want := image.Rect(0, 0, tt.width, len(tt.want)/tt.width); img.Bounds() != want {
+			t.Errorf("%s: got bounds %v, want %v", tt.name, img.Bounds(), want)
+			continue
+		}
+		for i, want := range tt.want {
+			x, y := i%tt.width, i/tt.width
+			gr, gg, gb, ga := img.At(x, y).RGBA()
+			wr, wg, wb, wa := want.RGBA()
+			if gr != wr || gg != wg || gb != wb || ga != wa {
+				t.Errorf("%s: got %v at (%d,%d), want %v", tt.name, img.At(x, y), x, y, want)
+			}
+		}
+
+		cfg, err := DecodePNMConfig(bytes.NewReader([]byte(tt.data)))
+		if err != nil || cfg.ColorModel != tt.model || cfg.Width != img.Bounds().Dx() || cfg.Height != img.Bounds().Dy() {
+			t.Errorf("%s: got config %+v (%v), want %dx%d", tt.name, cfg, err, img.Bounds().Dx(), img.Bounds().Dy())
+		}
+	}
+}
+
+func TestDecodePNMInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"ascii pgm", "P2\n1 1\n255\n0\n"},
+		{"pbm", "P4\n8 1\n\x00"},
+		{"zero width", "P5\n0 1\n255\n"},
+		{"zero maxval", "P5\n1 1\n0\n\x00"},
+		{"maxval above 16-bit", "P5\n1 1\n65536\n\x00\x00"},
+		{"not a number", "P5\n1 x\n255\n\x00"},
+		{"negative size", "P5\n-1 1\n255\n\x00"},
+		{"truncated header", "P5\n2 2"},
+		{"truncated comment", "P5\n# no end"},
+		{"truncated data", "P5\n2 2\n255\n\x00\x00\x00"},
+		{"truncated 16-bit data", "P5\n1 1\n65535\n\x00"},
+		{"truncated rgb data", "P6\n1 1\n255\n\x00\x00"},
+	}
+	for _, tt := range tests {
+		if img, err := DecodePNM(bytes.NewReader([]byte(tt.data))); err == nil {
+			t.Errorf("%s: got %v, want error", tt.name, img.Bounds())
+		}
+	}
+}
+
+func TestDecodePNMRegistered(t *testing.T) {
+	_, format, err := image.Decode(bytes.NewReader([]byte("P6\n1 1\n255\n\x01\x02\x03")))
+	if err != nil || format != "pnm" {
+		t.Errorf("got format %s (%v), want pnm", format, err)
+	}
+}
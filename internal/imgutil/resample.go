@@ -0,0 +1,130 @@
+//
+// resample.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package imgutil
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	drawx "golang.org/x/image/draw"
+)
+
+// Lanczos3 is the Lanczos resampling kernel with 3 lobes. It is sharper than CatmullRom but may ring around edges.
+var Lanczos3 = &drawx.Kernel{
+	Support: 3,
+	At: func(t float64) float64 {
+		if t == 0 {
+			return 1
+		}
+		if t < 0 {
+			t = -t
+		}
+		if t >= 3 {
+			return 0
+		}
+		x := math.Pi * t
+		return 3 * math.Sin(x) * math.Sin(x/3) / (x * x)
+	},
+}
+
+// ResizeWith resizes the image with the interpolator
+func ResizeWith(src image.Image, size image.Point, interp drawx.Interpolator) image.Image {
+	canvas := NewCanvasSameColor(src, image.Rect(0, 0, size.X, size.Y))
+	interp.Scale(canvas, canvas.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return canvas
+}
+
+// UnsharpMask sharpens the image by adding the difference between the image and its gaussian blur times amount
+func UnsharpMask(src image.Image, sigma float64, amount float64) image.Image {
+	b := src.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width == 0 || height == 0 || sigma <= 0 || amount <= 0 {
+		return src
+	}
+
+	// color channels in [0.0-1.0]
+	var planes [3][]float64
+	for i := range planes {
+		planes[i] = make([]float64, width*height)
+	}
+	alpha := make([]uint16, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA64Model.Convert(src.At(x+b.Min.X, y+b.Min.Y)).(color.NRGBA64)
+			i := y*width + x
+			planes[0][i] = float64(c.R) / ColorRange
+			planes[1][i] = float64(c.G) / ColorRange
+			planes[2][i] = float64(c.B) / ColorRange
+			alpha[i] = c.A
+		}
+	}
+
+	kernel := gaussianKernel(sigma)
+	canvas := NewCanvasSameColor(src, b)
+	blurred := make([][]float64, len(planes))
+	for i, plane := range planes {
+		blurred[i] = gaussianBlur(plane, width, height, kernel)
+	}
+	sharpen := func(i int, c int) uint16 {
+		v := planes[c][i] + amount*(planes[c][i]-blurred[c][i])
+		return uint16(math.Round(max(0, min(1, v)) * ColorRange))
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			canvas.Set(x+b.Min.X, y+b.Min.Y, color.NRGBA64{R: sharpen(i, 0), G: sharpen(i, 1), B: sharpen(i, 2), A: alpha[i]})
+		}
+	}
+	return canvas
+}
+
+// gaussianKernel returns the normalized 1D gaussian kernel with radius of 3 sigma
+func gaussianKernel(sigma float64) []float64 {
+	radius := max(1, int(math.Ceil(3*sigma)))
+	kernel := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// gaussianBlur blurs the plane horizontally then vertically. The edge pixels are repeated beyond the edges.
+func gaussianBlur(plane []float64, width int, height int, kernel []float64) []float64 {
+	radius := len(kernel) / 2
+	tmp := make([]float64, len(plane))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for k, w := range kernel {
+				sx := max(0, min(width-1, x+k-radius))
+				sum += w * plane[y*width+sx]
+			}
+			tmp[y*width+x] = sum
+		}
+	}
+	out := make([]float64, len(plane))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for k, w := range kernel {
+				sy := max(0, min(height-1, y+k-radius))
+				sum += w * tmp[sy*width+x]
+			}
+			out[y*width+x] = sum
+		}
+	}
+	return out
+}
@@ -9,8 +9,10 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
 )
 
 var verbose bool
@@ -19,31 +21,162 @@ func SetVerbose(enabled bool) {
 	verbose = enabled
 }
 
-var indentLevel int = 0
-var indent string
-var newlineAfterUnindent = false
+// writeMu serializes writes to stdout/stderr so lines from concurrent loggers are not interleaved
+var writeMu sync.Mutex
 
-func IndentLevel() int {
-	return indentLevel
+type entry struct {
+	isErr bool
+	line  string
 }
 
-func SetIndentLevel(level int) {
-	if level != indentLevel {
-		if level < indentLevel && newlineAfterUnindent {
-			fmt.Println("")
+// Logger prints indented log lines.
+// A buffered logger keeps the lines until Flush() so the lines of one task are printed together.
+type Logger struct {
+	mu                   sync.Mutex
+	buffered             bool
+	entries              []entry
+	indentLevel          int
+	indent               string
+	newlineAfterUnindent bool
+}
+
+var std = &Logger{}
+
+// Default returns the logger that prints directly to stdout/stderr
+func Default() *Logger {
+	return std
+}
+
+// NewBuffer returns a buffered logger starting at the current indent level of the default logger
+func NewBuffer() *Logger {
+	l := &Logger{buffered: true}
+	l.SetIndentLevel(IndentLevel())
+	l.newlineAfterUnindent = false
+	return l
+}
+
+func (l *Logger) write(isErr bool, line string) {
+	if l.buffered {
+		l.entries = append(l.entries, entry{isErr, line})
+		return
+	}
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	writeLine(isErr, line)
+}
+
+var output io.Writer = os.Stdout
+
+// SetOutput sets the writer of non-error log lines. The default is stdout.
+func SetOutput(w io.Writer) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	output = w
+}
+
+func writeLine(isErr bool, line string) {
+	w := output
+	if isErr {
+		w = os.Stderr
+	}
+	fmt.Fprint(w, line)
+}
+
+// Flush prints all buffered lines
+func (l *Logger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	for _, e := range l.entries {
+		writeLine(e.isErr, e.line)
+	}
+	l.entries = nil
+}
+
+// MoveTo appends buffered lines to dst and clears them
+func (l *Logger) MoveTo(dst *Logger) {
+	if l == nil || l == dst {
+		return
+	}
+	l.mu.Lock()
+	entries := l.entries
+	l.entries = nil
+	l.mu.Unlock()
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+	for _, e := range entries {
+		dst.write(e.isErr, e.line)
+	}
+	if len(entries) > 0 {
+		dst.newlineAfterUnindent = true
+	}
+}
+
+func (l *Logger) IndentLevel() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.indentLevel
+}
+
+func (l *Logger) SetIndentLevel(level int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level != l.indentLevel {
+		if level < l.indentLevel && l.newlineAfterUnindent {
+			l.write(false, "\n")
 		}
-		newlineAfterUnindent = false
+		l.newlineAfterUnindent = false
+	}
+	l.indentLevel = level
+	l.indent = strings.Repeat(" ", int(max(0, level))*4)
+}
+
+func (l *Logger) Indent() {
+	l.SetIndentLevel(l.IndentLevel() + 1)
+}
+
+func (l *Logger) Unindent() {
+	l.SetIndentLevel(l.IndentLevel() - 1)
+}
+
+func (l *Logger) Verbosef(format string, v ...any) {
+	if verbose {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.write(false, fmt.Sprintf("%sVerbose: %s\n", l.indent, fmt.Sprintf(format, v...)))
+		l.newlineAfterUnindent = true
 	}
-	indentLevel = level
-	indent = strings.Repeat(" ", int(max(0, level))*4)
+}
+
+func (l *Logger) Printf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(false, fmt.Sprintf("%s%s\n", l.indent, fmt.Sprintf(format, v...)))
+	l.newlineAfterUnindent = true
+}
+
+func (l *Logger) Errorf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(true, fmt.Sprintf("%sError: %s\n", l.indent, fmt.Sprintf(format, v...)))
+	l.newlineAfterUnindent = true
+}
+
+func IndentLevel() int {
+	return std.IndentLevel()
+}
+
+func SetIndentLevel(level int) {
+	std.SetIndentLevel(level)
 }
 
 func Indent() {
-	SetIndentLevel(indentLevel + 1)
+	std.Indent()
 }
 
 func Unindent() {
-	SetIndentLevel(indentLevel - 1)
+	std.Unindent()
 }
 
 func Verbose(str string) {
@@ -51,10 +184,7 @@ func Verbose(str string) {
 }
 
 func Verbosef(format string, v ...any) {
-	if verbose {
-		fmt.Fprintf(os.Stdout, "%sVerbose: %s\n", indent, fmt.Sprintf(format, v...))
-		newlineAfterUnindent = true
-	}
+	std.Verbosef(format, v...)
 }
 
 func Print(str string) {
@@ -62,8 +192,7 @@ func Print(str string) {
 }
 
 func Printf(format string, v ...any) {
-	fmt.Fprintf(os.Stdout, "%s%s\n", indent, fmt.Sprintf(format, v...))
-	newlineAfterUnindent = true
+	std.Printf(format, v...)
 }
 
 func Error(str string) {
@@ -71,8 +200,7 @@ func Error(str string) {
 }
 
 func Errorf(format string, v ...any) {
-	fmt.Fprintf(os.Stderr, "%sError: %s\n", indent, fmt.Sprintf(format, v...))
-	newlineAfterUnindent = true
+	std.Errorf(format, v...)
 }
 
 func Panic(str string) {
@@ -81,6 +209,6 @@ func Panic(str string) {
 
 func Panicf(format string, v ...any) {
 	s := fmt.Sprintf(format, v...)
-	fmt.Fprintf(os.Stderr, "%sError: %s\n", indent, s)
+	std.Errorf("%s", s)
 	panic(s)
 }
@@ -9,6 +9,7 @@ package util
 
 import (
 	"archive/zip"
+	"cmp"
 	"errors"
 	"fmt"
 	"io"
@@ -98,6 +99,56 @@ func ReplaceExt(path string, ext string) string {
 	}
 }
 
+// NaturalCompare compares two strings in natural order
+// where digit sequences are compared by their numeric values (ex. 2.png < 10.png).
+func NaturalCompare(a string, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		ca, cb := a[i], b[j]
+		if isDigit(ca) && isDigit(cb) {
+			// compare digit sequences by numeric values
+			si := i
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			sj := j
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na := strings.TrimLeft(a[si:i], "0")
+			nb := strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return cmp.Compare(len(na), len(nb))
+			}
+			if na != nb {
+				return strings.Compare(na, nb)
+			}
+			continue
+		}
+		la, lb := toLower(ca), toLower(cb)
+		if la != lb {
+			return cmp.Compare(la, lb)
+		}
+		i++
+		j++
+	}
+	if len(a)-i != len(b)-j {
+		return cmp.Compare(len(a)-i, len(b)-j)
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
+
 func CreateTemplate(name string, t string) *template.Template {
 	return template.Must(template.New(name).Parse(t))
 }
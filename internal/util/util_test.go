@@ -0,0 +1,53 @@
+//
+// util_test.go
+// Copyright (C) 2024 Teerapap Changwichukarn <teerapap.c@gmail.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package util
+
+import (
+	"testing"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"a", "a", 0},
+		{"2.png", "10.png", -1},
+		{"page9", "page10", -1},
+		{"99999999999999999999", "100000000000000000000", -1}, // beyond int64
+		// leading zeros
+		{"007", "8", -1},
+		{"0010", "9", 1},
+		{"page001.png", "page002.png", -1},
+		{"page010.png", "page9.png", 1},
+		// equal numeric values with different padding are ordered but not equal
+		{"page002", "page2", -1},
+		{"0", "00", -1},
+		{"file010b", "file10a", 1},
+		// mixed case
+		{"Page1", "page2", -1},
+		{"b", "A", 1},
+		{"Chapter10", "chapter9", 1},
+		{"ABC", "abc", -1},
+		// digit and non-digit boundaries
+		{"a1", "ab", -1},
+		{"1a", "a", -1},
+		{"img", "img1", -1},
+		{"12a", "12", 1},
+		{"v1.10", "v1.9", 1},
+		{"a1b2", "a1b10", -1},
+	}
+	for _, tt := range tests {
+		if got := NaturalCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("NaturalCompare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := NaturalCompare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("NaturalCompare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
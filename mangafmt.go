@@ -5,11 +5,18 @@ import (
 	"fmt"
 	"image/color"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/teerapap/mangafmt/internal/book"
 	"github.com/teerapap/mangafmt/internal/book/format"
+	"github.com/teerapap/mangafmt/internal/config"
 	"github.com/teerapap/mangafmt/internal/imgutil"
 	"github.com/teerapap/mangafmt/internal/log"
 	"github.com/teerapap/mangafmt/internal/util"
@@ -21,19 +28,32 @@ var verbose bool
 var version bool
 var workDir string
 var pageRangeStr string
-var pageRange = book.NewPageRange()
 var bookTitle string
 var bgColorStr string
+var autoBackground bool
 var bookConfig book.BookConfig
 var fuzzP float64
 var trimConfig book.TrimConfig
 var bgDistortStr string
+var spreadForceStr string
+var spreadNeverStr string
 var spreadConfig book.SpreadConfig
 var targetSize book.Size
+var splitConfig book.SplitConfig
+var rotation book.Rotation
+var resizeConfig book.ResizeConfig
+var spreadParity bool
+var trimPageStrs stringList
+var blankConfig book.BlankConfig
 var grayscaleStr string
 var grayConfig book.GrayscaleConfig
 var outputFile string
 var outputFormat format.OutputFormat
+var jobs int
+var configFile string
+var profileName string
+var dryRun bool
+var reportFormat string
 
 func init() {
 	flag.Usage = func() {
@@ -46,33 +66,76 @@ func init() {
 	flag.BoolVar(&version, "version", false, "Show version")
 	flag.StringVar(&workDir, "work-dir", "", "Work directory path. Unspecified or blank means using system temp path")
 	flag.StringVar(&pageRangeStr, "pages", "1-", "Page range (Ex. '4-10, 15, 39-'). Default is all pages. Open right range means to the end.")
-	flag.StringVar(&bookTitle, "title", "", "Book title. This affects epub/kepub output. Unspecified or blank means using filename without extension")
+	flag.StringVar(&bookTitle, "title", "", "Book title. This affects epub/kepub output. Unspecified or blank means using filename without extension. Ignored with multiple input files")
 	flag.Float64Var(&bookConfig.Density, "density", 300.0, "Output density (DPI)")
-	flag.StringVar(&bgColorStr, "background", "#FFFFFF,#000000", "Background color(s) separated by comma. The first color is the main background color.")
+	flag.Var(&bookConfig.ExtractFormat, "extract-format", "Intermediate image format of extracted PDF pages. The supported formats\n\t- jpeg (default)\n\t- png (lossless)\n\t- pnm (lossless, PPM/PGM)\n\t- tiff (lossless)")
+	flag.StringVar(&bookConfig.Extractor, "extractor", "auto", fmt.Sprintf("PDF page extractor. The supported extractors are %s.\n'auto' extracts embedded images natively and falls back to the first installed external extractor for pages with vector content.", strings.Join(book.ExtractorNames(), ", ")))
+	flag.StringVar(&bgColorStr, "background", "#FFFFFF,#000000", "Background color(s) separated by comma. The first color is the main background color.\n'auto' detects the background colors from the border of sampled pages of each book")
 	flag.BoolVar(&bookConfig.IsRTL, "rtl", false, "Right-to-left read direction (ex. Japanese manga)")
 	flag.BoolVar(&bookConfig.IsRTL, "right-to-left", false, "Right-to-left read direction (ex. Japanese manga)")
 	flag.Float64Var(&fuzzP, "fuzz", 0.1, "Color fuzz (percentage)[0.0-1.0]")
+	flag.BoolVar(&blankConfig.Remove, "remove-blank", false, "Remove blank pages (ex. inside covers and separator pages)")
+	flag.Float64Var(&blankConfig.FuzzP, "blank-fuzz", 0.1, "Color fuzz (percentage) to check blank page[0.0-1.0]")
+	flag.Float64Var(&blankConfig.MaxCoverageP, "blank-coverage", 0.001, "Maximum non-background pixels (percentage) of blank page[0.0-1.0]")
 	flag.BoolVar(&trimConfig.Enabled, "trim", true, "Enable trim edge")
 	flag.Float64Var(&trimConfig.MinSizeP, "trim-min-size", 0.85, "Minimum size after trimmed (percentage)[0.0-1.0]")
-	flag.IntVar(&trimConfig.Margin, "trim-margin", 10, "Safety trim margin (pixel)")
+	trimConfig.Margin = book.AllSides(10)
+	flag.Var(&trimConfig.Margin, "trim-margin", "Safety trim margin (pixel). One value or four values of top,bottom,inner,outer sides separated by comma.\nThe inner side is the binding side resolved from page number parity and --rtl")
+	trimConfig.MaxTrimP = book.AllSides(1.0)
+	flag.Var(&trimConfig.MaxTrimP, "trim-max", "Maximum trim (percentage of page size)[0.0-1.0]. One value or four values of top,bottom,inner,outer sides separated by comma")
+	flag.Var(&trimPageStrs, "trim-page", "Override trim options of pages in PAGES:key=value;key=value format (ex. \"1-3,10:margin=0,0,20,0;max-trim=0.1\").\nThe supported keys are trim, margin, max-trim and min-size. Can be repeated")
+	flag.Var(&trimConfig.Mode, "trim-mode", "Trim mode. The supported modes are\nedge - stop at the first pixel which is not background\ndensity - by ink density per row and column ignoring specks, thin lines near the edges and header/footer content")
+	flag.IntVar(&trimConfig.MinComponent, "trim-min-component", 25, "Ignore connected ink smaller than this (pixel) unless it is among other ink like screentone dots. Density trim mode only")
+	flag.Float64Var(&trimConfig.MinDensityP, "trim-density", 0.0, "Minimum ink pixels of content row or column (percentage)[0.0-1.0]. Density trim mode only")
+	flag.Float64Var(&trimConfig.HeaderP, "trim-header", 0.0, "Ignore content entirely within this top band (percentage of page height)[0.0-1.0]. Density trim mode only")
+	flag.Float64Var(&trimConfig.FooterP, "trim-footer", 0.0, "Ignore content entirely within this bottom band (ex. page numbers)(percentage of page height)[0.0-1.0]. Density trim mode only")
+	flag.IntVar(&trimConfig.MaxEdgeLine, "trim-edge-line", 3, "Ignore long lines up to this thickness near the page edges (ex. scanner edges)(pixel). Density trim mode only")
+	flag.BoolVar(&trimConfig.AutoBackground, "trim-auto-background", false, "Trim each side against the background color (one of --background colors) found on its border. Otherwise, trim against the main background color")
+	flag.Var(&trimConfig.Uniform, "trim-uniform", "Trim all pages by a book-wide trim box measured in a first pass. The supported modes are\nnone - trim each page by its own trim box\nunion - the smallest box containing trim boxes of all pages\nmedian - the median of each side of trim boxes of all pages. Content of a page within --trim-outlier beyond the box is cut")
+	flag.BoolVar(&trimConfig.UniformParity, "trim-uniform-parity", false, "Use separate book-wide trim boxes for odd and even pages")
+	flag.Float64Var(&trimConfig.OutlierP, "trim-outlier", 0.05, "Page whose trim box side differs from the book-wide median by more than this (percentage of page size) uses its own trim box[0.0-1.0]")
 	flag.BoolVar(&spreadConfig.Enabled, "spread", true, "Enable double-page spread detection and connection")
 	flag.UintVar(&spreadConfig.EdgeWidth, "spread-edge", 2, "Edge width for double-page spread detection (pixel)")
 	flag.UintVar(&spreadConfig.EdgeMargin, "spread-margin", 2, "Safety margin before edge width (pixel)")
 	flag.StringVar(&bgDistortStr, "spread-bg-distortion", "0.4,0.2", "A page is considered a single page if the distortion between its edge and background color are less than this threshold (percentage)[0.0-1.0].\nMultiple values are separated by comma. It should match with `--background` otherwise the last value is used for the rest of the list.")
 	flag.Float64Var(&spreadConfig.LrDistort, "spread-lr-distortion", 0.4, "Two pages are considered double-page spread if the distortion between their edges are less than this threshold (percentage)[0.0-1.0]")
+	flag.UintVar(&spreadConfig.MaxOffset, "spread-max-offset", 8, "Maximum vertical offset between two pages to search for the best match (pixel)")
+	flag.Float64Var(&spreadConfig.MaxScaleP, "spread-max-scale", 0.02, "Two pages with different heights are rescaled to match if the difference is less than this threshold (percentage)[0.0-1.0]")
+	flag.UintVar(&spreadConfig.MaxOverlap, "spread-max-overlap", 0, "Maximum width of the artwork repeated on both sides of the fold to be blended when connecting two pages (pixel). 0 means no overlap detection")
+	flag.Float64Var(&spreadConfig.MinConfidence, "spread-confidence", 0, "Two pages are considered double-page spread if the confidence combining edge similarity and gradient correlation is more than this threshold (percentage)[0.0-1.0].\n0 means using --spread-lr-distortion threshold only")
+	flag.Float64Var(&spreadConfig.GradientWeight, "spread-gradient-weight", 0.5, "Weight of the gradient correlation of both edges in the confidence (percentage)[0.0-1.0]")
+	flag.StringVar(&spreadForceStr, "spread-force", "", "Page range (Ex. '12-13, 88-89') of pairs of pages which are always connected as double-page spread. Both pages in a pair must be in the range")
+	flag.StringVar(&spreadNeverStr, "spread-never", "", "Page range (Ex. '40-41') of pairs of pages which are never connected as double-page spread. Both pages in a pair must be in the range")
+	flag.Var(&spreadConfig.Mode, "spread-mode", "Output of double-page spread. The supported modes\n\t- rotate (default): rotate the spread to fit the screen\n\t- split: split the spread back into two pages\n\t- both: the rotated spread followed by the two pages\n\t- fit: keep landscape and letterbox into the screen")
+	flag.BoolVar(&spreadParity, "spread-parity", false, "Insert or drop blank pages so that both halves of a double-page spread land on facing pages of a two-page reader.\nThe page sides are written to EPUB/KEPUB output")
+	flag.Var(&rotation, "rotate", "Rotation direction of a page which does not match screen orientation. The supported directions\n\t- ccw (default): counter-clockwise\n\t- cw: clockwise")
+	flag.BoolVar(&resizeConfig.Upscale, "upscale", false, "Enlarge pages smaller than the screen size to fit the screen")
+	flag.Var(&resizeConfig.Filter, "resize-filter", "Resampling filter to resize pages. The supported filters are catmullrom, lanczos and bilinear")
+	flag.Float64Var(&resizeConfig.Sharpen, "sharpen", 0, "Amount of unsharp mask applied after resizing pages (ex. 0.5). 0 means no sharpening")
+	flag.Float64Var(&resizeConfig.SharpenSigma, "sharpen-radius", 1.0, "Radius (gaussian sigma) of unsharp mask (pixel)")
+	flag.BoolVar(&splitConfig.Enabled, "split-wide", false, "Split a landscape page (ex. double-page spread in the source) into two portrait pages at the gutter")
+	flag.BoolVar(&splitConfig.KeepSpread, "split-keep-spread", false, "Keep the whole rotated spread after the split halves. It requires --split-wide")
+	flag.Float64Var(&splitConfig.GutterBandP, "split-gutter-band", 0.1, "Width of the band in the middle of a wide page to search for the gutter (percentage)[0.0-1.0]")
+	flag.Float64Var(&splitConfig.MinGutterP, "split-gutter-min", 0.9, "A column is considered a gutter if its background pixels are more than this ratio (percentage)[0.0-1.0]. Otherwise, the page is split in the middle")
 	flag.UintVar(&targetSize.Width, "width", 1264, "Output screen width (pixel)")
 	flag.UintVar(&targetSize.Height, "height", 1680, "Output screen heigt (pixel)")
 	flag.StringVar(&grayscaleStr, "grayscale", "2-", "Page range (Ex. '4-10, 15, 39-') to convert to grayscale. Default is all pages except the first page(cover). 'false' means no grayscale conversion")
 	flag.UintVar(&grayConfig.ColorDepth, "grayscale-depth", 4, "Grayscale color depth in number of bits. Possible values are 1, 2, 4, 8, 16 bits. No upscale if source image is in lower depth.")
 	flag.Var(&outputFormat, "format", "Output file format. The supported formats\n\t- raw (default)\n\t- cbz\n\t- epub\n\t- kepub")
-	flag.StringVar(&outputFile, "output", "", "Output file. Unspecified or blank means using the same file name as input file")
+	flag.IntVar(&jobs, "jobs", 1, "Number of pages processed in parallel. 0 means the number of CPUs")
+	flag.IntVar(&jobs, "j", 1, "Number of pages processed in parallel. 0 means the number of CPUs")
+	flag.BoolVar(&dryRun, "dry-run", false, "Only detect double-page spreads in the page range and print the report without writing output")
+	flag.StringVar(&reportFormat, "report-format", "table", "Format of the --dry-run report. The supported formats are table and json. json is printed to stdout while logs are printed to stderr")
+	flag.StringVar(&configFile, "config", "", "Config file path. Unspecified or blank means using mangafmt/config.json in the user config directory if exists")
+	flag.StringVar(&profileName, "profile", "", fmt.Sprintf("Profile name in the config file or one of the built-in device profiles (%s). Command-line options override the profile", strings.Join(config.BuiltinProfileNames(), ", ")))
+	flag.StringVar(&outputFile, "output", "", "Output file. Unspecified or blank means using the same file name as input file. With multiple input files, this is the output directory")
 }
 
 func helpUsage(msg string) {
 	if msg != "" {
 		log.Error(msg)
 	}
-	fmt.Fprintf(flag.CommandLine.Output(), "%s [options] <input_pdf_file>\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "%s [options] <input_file|input_image_dir|glob>...\n", os.Args[0])
 	flag.PrintDefaults()
 	if msg != "" {
 		os.Exit(1)
@@ -95,6 +158,47 @@ func handleExit() {
 	}
 }
 
+// defaultOutputFile returns the output file path named after the input file. It is in outputDir if specified.
+func defaultOutputFile(inputFile string, outputDir string, f format.OutputFormat) string {
+	name := inputFile
+	if fi, err := os.Stat(inputFile); err != nil || !fi.IsDir() {
+		// only strip the extension of a file. A directory of images is named as is (ex. Vol.01)
+		name = util.NameWithoutExt(inputFile)
+	}
+	if outputDir != "" {
+		name = filepath.Join(outputDir, filepath.Base(name))
+	}
+	if f.Ext() == "" {
+		if name == inputFile {
+			// do not write into the input directory
+			return name + "-out"
+		}
+		return name
+	}
+	outputFile := fmt.Sprintf("%s.%s", name, f.Ext())
+	if outputFile == inputFile {
+		// do not overwrite the input file (ex. cbz to cbz)
+		outputFile = fmt.Sprintf("%s-out.%s", name, f.Ext())
+	}
+	return outputFile
+}
+
+// batchOutputFiles returns the output file of each input file in batch mode.
+// It fails if two input files have the same output file (ex. a/book.pdf and b/book.pdf with --output, or x.pdf and x.cbz)
+func batchOutputFiles(inputFiles []string, outputDir string, f format.OutputFormat) ([]string, error) {
+	outputFiles := make([]string, len(inputFiles))
+	inputOf := make(map[string]string, len(inputFiles))
+	for i, inputFile := range inputFiles {
+		output := defaultOutputFile(inputFile, outputDir, f)
+		if other, ok := inputOf[output]; ok {
+			return nil, fmt.Errorf("%s and %s have the same output file %s", other, inputFile, output)
+		}
+		inputOf[output] = inputFile
+		outputFiles[i] = output
+	}
+	return outputFiles, nil
+}
+
 func parseColorHexList(str string) ([]color.Color, error) {
 	parts := strings.Split(str, ",")
 	res := make([]color.Color, 0, len(parts))
@@ -124,12 +228,152 @@ func parseFloatList(str string) ([]float64, error) {
 	return res, nil
 }
 
+// stringList is a flag which can be repeated
+type stringList []string
+
+func (l stringList) String() string {
+	return strings.Join(l, " ")
+}
+
+func (l *stringList) Set(val string) error {
+	*l = append(*l, val)
+	return nil
+}
+
+func joinInts(nums []int) string {
+	parts := make([]string, 0, len(nums))
+	for _, n := range nums {
+		parts = append(parts, strconv.Itoa(n))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// flagAliases maps short option names to their long names
+var flagAliases = map[string]string{
+	"h":             "help",
+	"v":             "verbose",
+	"j":             "jobs",
+	"right-to-left": "rtl",
+}
+
+// repeatableFlags are options which can be repeated. A list value in the config file sets the option once per element.
+var repeatableFlags = map[string]bool{
+	"trim-page": true,
+}
+
+// applyConfig sets options from the config file defaults and the selected profile
+// unless they are set on the command line.
+func applyConfig() error {
+	path := strings.TrimSpace(configFile)
+	required := path != ""
+	if !required {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			log.Verbosef("No user config directory: %s", err)
+			return nil
+		}
+	}
+	c, err := config.Load(path, required)
+	if err != nil {
+		return err
+	}
+	if c.Defaults != nil || c.Profiles != nil {
+		log.Verbosef("Config file: %s", path)
+	}
+
+	setOnCmdLine := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		name := f.Name
+		if long, ok := flagAliases[name]; ok {
+			name = long
+		}
+		setOnCmdLine[name] = true
+	})
+	apply := func(p config.Profile, source string) error {
+		opts, err := p.Options(flagAliases, repeatableFlags)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		for _, opt := range opts {
+			name, value := opt[0], opt[1]
+			if name == "config" || flag.Lookup(name) == nil {
+				return fmt.Errorf("%s: unknown option %s", source, name)
+			}
+			if setOnCmdLine[name] {
+				continue
+			}
+			if err := flag.Set(name, value); err != nil {
+				return fmt.Errorf("%s: setting option %s=%s: %w", source, name, value, err)
+			}
+			log.Verbosef("Option %s=%s from %s", name, value, source)
+		}
+		return nil
+	}
+
+	if err := apply(c.Defaults, "config defaults"); err != nil {
+		return err
+	}
+	if name := strings.TrimSpace(profileName); name != "" {
+		p, err := c.Profile(name)
+		if err != nil {
+			return err
+		}
+		log.Printf("Using profile %s", name)
+		if err := apply(p, fmt.Sprintf("profile %s", name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expandInputs(args []string) ([]string, error) {
+	inputFiles := make([]string, 0, len(args))
+	for _, arg := range args {
+		paths := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("expanding pattern %s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match pattern %s", arg)
+			}
+			slices.SortFunc(matches, util.NaturalCompare)
+			paths = matches
+		}
+		for _, path := range paths {
+			files, err := book.ExpandInputPath(path)
+			if err != nil {
+				return nil, fmt.Errorf("expanding input %s: %w", path, err)
+			}
+			for _, file := range files {
+				file, err = util.IsReadableFile(file)
+				if err != nil {
+					return nil, fmt.Errorf("checking input file path: %w", err)
+				}
+				if !slices.Contains(inputFiles, file) {
+					inputFiles = append(inputFiles, file)
+				}
+			}
+		}
+	}
+	return inputFiles, nil
+}
+
+type bookResult struct {
+	inputFile   string
+	outputFile  string
+	inputPages  int
+	outputPages int
+	elapsed     time.Duration
+	err         error
+}
+
 func main() {
 	defer handleExit()
 
 	// Parse command-line
 	flag.Parse()
-	inputFile := flag.Arg(0)
 	log.SetVerbose(verbose)
 
 	log.Verbosef("mangafmt-%s", util.AppVersion)
@@ -141,156 +385,275 @@ func main() {
 	} else if version {
 		showVersion()
 		os.Exit(0)
-	} else if inputFile == "" {
+	} else if flag.NArg() == 0 {
 		flag.Usage()
 		os.Exit(1)
 	}
-	inputFile = util.Must1(util.IsReadableFile(inputFile))("checking input file path")
-	log.Verbosef("Input: %s", inputFile)
+	util.Must(applyConfig())("loading config")
+	log.SetVerbose(verbose)
+	inputFiles := util.Must1(expandInputs(flag.Args()))("checking input files")
+	batch := len(inputFiles) > 1
 	outputFile = strings.TrimSpace(outputFile)
-	if outputFile == "" {
-		outputFile = util.ReplaceExt(inputFile, outputFormat.Ext())
-	} else {
-		outputFile = util.Must1(util.IsWritableFile(outputFile))("checking output file path")
+	bookTitle = strings.TrimSpace(bookTitle)
+	outputDir := ""
+	if batch {
+		log.Printf("Total Number of Books: %d", len(inputFiles))
+		if outputFile != "" {
+			// output is a directory in batch mode
+			outputDir = util.Must1(util.IsWritableFile(outputFile))("checking output directory path")
+			util.Must(os.MkdirAll(outputDir, 0750))("creating output directory")
+		}
+		if bookTitle != "" {
+			log.Printf("Ignore --title because there are multiple input files")
+			bookTitle = ""
+		}
 	}
-	log.Verbosef("Output: %s", outputFile)
 
-	bookConfig.BgColor = util.Must1(parseColorHexList(bgColorStr))("checking background color")
+	if autoBackground = strings.EqualFold(strings.TrimSpace(bgColorStr), "auto"); !autoBackground {
+		bookConfig.BgColor = util.Must1(parseColorHexList(bgColorStr))("checking background color")
+	}
 	trimConfig.MinSizeP = max(min(trimConfig.MinSizeP, 1.0), 0.0)
+	trimConfig.OutlierP = max(min(trimConfig.OutlierP, 1.0), 0.0)
+	trimConfig.MinDensityP = max(min(trimConfig.MinDensityP, 1.0), 0.0)
+	trimConfig.HeaderP = max(min(trimConfig.HeaderP, 1.0), 0.0)
+	trimConfig.FooterP = max(min(trimConfig.FooterP, 1.0), 0.0)
 	spreadConfig.BgDistort = util.Must1(parseFloatList(bgDistortStr))("checking spread background distortion threshold")
 	fuzzP = max(min(fuzzP, 1.0), 0.0)
+	blankConfig.FuzzP = max(min(blankConfig.FuzzP, 1.0), 0.0)
+	blankConfig.MaxCoverageP = max(min(blankConfig.MaxCoverageP, 1.0), 0.0)
+	splitConfig.GutterBandP = max(min(splitConfig.GutterBandP, 1.0), 0.0)
+	spreadConfig.MaxScaleP = max(min(spreadConfig.MaxScaleP, 1.0), 0.0)
+	spreadConfig.MinConfidence = max(min(spreadConfig.MinConfidence, 1.0), 0.0)
+	spreadConfig.GradientWeight = max(min(spreadConfig.GradientWeight, 1.0), 0.0)
 	util.Must(book.IsSupportedColorDepth(grayConfig.ColorDepth))("checking grayscale color depth")
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	reportFormat = strings.ToLower(strings.TrimSpace(reportFormat))
+	util.Assert(reportFormat == "table" || reportFormat == "json", fmt.Sprintf("unknown report format: %s", reportFormat))
+	if dryRun && reportFormat == "json" {
+		log.SetOutput(os.Stderr)
+	}
+
+	if !batch {
+		inputFile := inputFiles[0]
+		if outputFile == "" {
+			outputFile = defaultOutputFile(inputFile, "", outputFormat)
+		} else {
+			outputFile = util.Must1(util.IsWritableFile(outputFile))("checking output file path")
+		}
+		res := convertBook(inputFile, outputFile)
+		util.Must(res.err)(fmt.Sprintf("converting %s", inputFile))
+		return
+	}
+
+	outputFiles := util.Must1(batchOutputFiles(inputFiles, outputDir, outputFormat))("checking output files")
+	results := make([]bookResult, 0, len(inputFiles))
+	baseWorkDir := workDir
+	for i, inputFile := range inputFiles {
+		log.Printf("Converting book....(%d/%d) %s", i+1, len(inputFiles), inputFile)
+		log.Indent()
+		workDir = baseWorkDir
+		res := convertBook(inputFile, outputFiles[i])
+		if res.err != nil {
+			log.Errorf("converting %s: %s", inputFile, res.err)
+		}
+		results = append(results, res)
+		log.Unindent()
+	}
+	printSummary(results)
+	for _, res := range results {
+		if res.err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// convertBook converts one input file to the output file.
+// It does not panic so the other books in the batch can continue.
+func convertBook(inputFile string, outputFile string) (res bookResult) {
+	res = bookResult{inputFile: inputFile, outputFile: outputFile}
+	start := time.Now()
+	indentLevel := log.IndentLevel()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Verbosef("%s", debug.Stack())
+			res.err = fmt.Errorf("%v", r)
+		}
+		log.SetIndentLevel(indentLevel)
+		res.elapsed = time.Since(start)
+	}()
+	log.Verbosef("Input: %s", inputFile)
+	log.Verbosef("Output: %s", outputFile)
 
 	// Load input book file
-	theBook := util.Must1(book.NewBook(inputFile, bookConfig))("loading book")
-	bookTitle = strings.TrimSpace(bookTitle)
+	theBook, err := book.NewBook(inputFile, bookConfig)
+	if err != nil {
+		res.err = fmt.Errorf("loading book: %w", err)
+		return
+	}
+	defer theBook.Close()
 	if bookTitle != "" {
 		theBook.Title = bookTitle
 	}
 	log.Printf("Total Number of Pages: %d", theBook.PageCount)
 
 	// Parse page range arguments
-	util.Must(pageRange.Parse(pageRangeStr, theBook.PageCount))(fmt.Sprintf("parsing page range(%s)", pageRangeStr))
+	pageRange := book.NewPageRange()
+	if err := pageRange.Parse(pageRangeStr, theBook.PageCount); err != nil {
+		res.err = fmt.Errorf("parsing page range(%s): %w", pageRangeStr, err)
+		return
+	}
+	grayConfig.PageRange = nil
 	if strings.ToLower(grayscaleStr) != "false" {
 		grayConfig.PageRange = book.NewPageRange()
-		util.Must(grayConfig.PageRange.Parse(grayscaleStr, theBook.PageCount))(fmt.Sprintf("parsing grayscale page range(%s)", grayscaleStr))
+		if err := grayConfig.PageRange.Parse(grayscaleStr, theBook.PageCount); err != nil {
+			res.err = fmt.Errorf("parsing grayscale page range(%s): %w", grayscaleStr, err)
+			return
+		}
+	}
+	spreadConfig.Force, spreadConfig.Never = nil, nil
+	if spreadForceStr = strings.TrimSpace(spreadForceStr); spreadForceStr != "" {
+		spreadConfig.Force = book.NewPageRange()
+		if err := spreadConfig.Force.Parse(spreadForceStr, theBook.PageCount); err != nil {
+			res.err = fmt.Errorf("parsing spread force page range(%s): %w", spreadForceStr, err)
+			return
+		}
+	}
+	if spreadNeverStr = strings.TrimSpace(spreadNeverStr); spreadNeverStr != "" {
+		spreadConfig.Never = book.NewPageRange()
+		if err := spreadConfig.Never.Parse(spreadNeverStr, theBook.PageCount); err != nil {
+			res.err = fmt.Errorf("parsing spread never page range(%s): %w", spreadNeverStr, err)
+			return
+		}
+		for _, p := range spreadConfig.Never.All() {
+			if spreadConfig.Never.Contains(p+1) && spreadConfig.Force != nil && spreadConfig.Force.Contains(p) && spreadConfig.Force.Contains(p+1) {
+				res.err = fmt.Errorf("pages %d-%d are in both --spread-force and --spread-never", p, p+1)
+				return
+			}
+		}
+	}
+	trimConfig.Overrides = nil
+	for _, str := range trimPageStrs {
+		o, err := book.ParseTrimOverride(str, theBook.PageCount)
+		if err != nil {
+			res.err = fmt.Errorf("parsing trim page override(%s): %w", str, err)
+			return
+		}
+		trimConfig.Overrides = append(trimConfig.Overrides, o)
+	}
+	res.inputPages = pageRange.PageCount()
+
+	if autoBackground {
+		if err := detectBackground(theBook, pageRange); err != nil {
+			res.err = fmt.Errorf("detecting background colors: %w", err)
+			return
+		}
+	}
+
+	if dryRun {
+		res.outputFile = "(dry run)"
+		report, err := detectSpreads(theBook, pageRange)
+		if err != nil {
+			res.err = fmt.Errorf("detecting spreads: %w", err)
+			return
+		}
+		if err := printSpreadReport(report); err != nil {
+			res.err = fmt.Errorf("printing spread report: %w", err)
+		}
+		return
 	}
 
 	// Create work dir
-	util.Must1(util.CreateWorkDir(&workDir, true))("creating work directory")
+	if _, err := util.CreateWorkDir(&workDir, true); err != nil {
+		res.err = fmt.Errorf("creating work directory: %w", err)
+		return
+	}
 	defer os.RemoveAll(workDir)
 	log.Verbosef("Work directory: %s", workDir)
 
-	// For loop each page
-	partials := pageRange.PageCount() != theBook.PageCount
-	if partials {
+	// Measure book-wide trim boxes
+	trimConfig.UniformBoxes = nil
+	if trimConfig.Enabled && trimConfig.Uniform != book.UniformTrimNone {
+		log.Printf("Measuring trim boxes of all pages")
+		log.Indent()
+		samples, err := measureTrimBoxes(theBook, pageRange, jobs)
+		if err != nil {
+			res.err = fmt.Errorf("measuring trim boxes: %w", err)
+			return
+		}
+		trimConfig.UniformBoxes = book.NewUniformTrim(samples, trimConfig)
+		trimConfig.UniformBoxes.Log()
+		log.Unindent()
+	}
+
+	// Process pages
+	if pageRange.PageCount() != theBook.PageCount {
 		log.Printf("Start processing page(s) in range %s. Total %d page(s).", pageRange, pageRange.PageCount())
 	} else {
 		log.Printf("Start processing. Total %d page(s).", pageRange.PageCount())
 	}
 	log.Indent()
-
-	outPages := make([]format.Page, 0, theBook.PageCount)
-	for page, i := 1, 1; page <= theBook.PageCount; {
-		if !pageRange.Contains(page) {
-			page += 1
-			continue
-		}
-		if partials {
-			log.Printf("Processing page %d....(%d/%d)", page, i, pageRange.PageCount())
-		} else {
-			log.Printf("Processing page....(%d/%d)", page, theBook.PageCount)
-		}
-		log.Indent()
-
-		outPage, processed := util.Must2(processEachPage(theBook, pageRange, page))(fmt.Sprintf("processing page %d", page))
-		outPages = append(outPages, *outPage)
-		page += processed
-		i += processed
-		log.Verbosef("next input page = %d, next output page = %d", page, len(outPages))
-
-		log.Unindent()
+	outPages, removed, err := processPages(theBook, pageRange, jobs)
+	if err != nil {
+		res.err = fmt.Errorf("processing pages: %w", err)
+		return
 	}
 	log.Unindent()
 	log.Printf("Done processing.")
 	log.Printf("Total Input %d page(s). Total Output %d pages(s).", pageRange.PageCount(), len(outPages))
+	if len(removed) > 0 {
+		log.Printf("Removed %d blank page(s): %s", len(removed), joinInts(removed))
+	}
+
+	if spreadParity {
+		if outPages, err = format.ArrangeSpreads(theBook, outPages, workDir); err != nil {
+			res.err = fmt.Errorf("arranging pages on two-page spreads: %w", err)
+			return
+		}
+	}
 
 	// Packaging
 	switch outputFormat {
 	case format.RAW:
-		util.Must(format.SaveAsRaw(outPages, outputFile))("saving in raw format")
+		err = format.SaveAsRaw(outPages, outputFile)
 	case format.CBZ:
-		util.Must(format.SaveAsCBZ(outPages, outputFile))("saving in cbz format")
+		err = format.SaveAsCBZ(outPages, outputFile)
 	case format.EPUB:
-		util.Must(format.SaveAsEPUB(theBook, outPages, outputFile))("saving in epub format")
+		err = format.SaveAsEPUB(theBook, outPages, outputFile)
 	case format.KEPUB:
-		util.Must(format.SaveAsKEPUB(theBook, outPages, outputFile))("saving in kepub format")
+		err = format.SaveAsKEPUB(theBook, outPages, outputFile)
 	}
-	log.Printf("Total Input %d page(s). Total Output %d pages(s).", pageRange.PageCount(), len(outPages))
-}
-
-func processEachPage(theBook *book.Book, pr *book.PageRange, pageNo int) (*format.Page, int, error) {
-	processed := 0
-	current, err := theBook.LoadPage(pageNo)
 	if err != nil {
-		return nil, 0, fmt.Errorf("loading page %d: %w", pageNo, err)
+		res.err = fmt.Errorf("saving in %s format: %w", outputFormat, err)
+		return
 	}
-	defer current.Destroy()
-
-	processed += 1
-
-	// Look ahead next page
-	if pr.Contains(pageNo+1) && spreadConfig.Enabled { // has next page
-		// Read next page
-		next, err := theBook.LoadPage(pageNo + 1)
-		if err != nil {
-			return nil, 0, fmt.Errorf("loading next page %d: %w", pageNo+1, err)
-		}
-		defer next.Destroy()
+	res.outputPages = len(outPages)
+	log.Printf("Total Input %d page(s). Total Output %d pages(s).", pageRange.PageCount(), len(outPages))
+	return
+}
 
-		// Check if the next page can merge with current page
-		left, right := current.LeftRight(next)
-		connected, err := left.IsDoublePageSpread(right, spreadConfig)
-		if err != nil {
-			return nil, 0, fmt.Errorf("checking if two pages are double-page spread: %w", err)
+func printSummary(results []bookResult) {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tStatus\tInput Pages\tOutput Pages\tTime\tInput\tOutput")
+	failed := 0
+	for i, res := range results {
+		status := "OK"
+		output := res.outputFile
+		if res.err != nil {
+			status = "FAILED"
+			output = res.err.Error()
+			failed += 1
 		}
-		if connected {
-			// connect two pages
-			if current, err = left.Connect(right); err != nil {
-				return nil, 0, fmt.Errorf("connecting two pages: %w", err)
-			}
-			defer current.Destroy()
-			processed += 1
-		}
-	}
-
-	// Trim image with fuzz
-	if err := current.Trim(trimConfig, fuzzP); err != nil {
-		return nil, 0, fmt.Errorf("trimming page: %w", err)
-	}
-
-	// Resize page to aspect fit screen
-	if err := current.ResizeToFit(targetSize); err != nil {
-		return nil, 0, fmt.Errorf("resizing page to fit to screen: %w", err)
-	}
-
-	// Convert to grayscale
-	if err := current.ConvertToGrayscale(grayConfig); err != nil {
-		return nil, 0, fmt.Errorf("converting page to grayscale: %w", err)
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\t%s\t%s\n", i+1, status, res.inputPages, res.outputPages, res.elapsed.Round(time.Millisecond), res.inputFile, output)
 	}
+	w.Flush()
 
-	// Write to filesystem
-	outFile, mediaType, err := current.WriteFile(workDir)
-	if err != nil {
-		return nil, 0, fmt.Errorf("writing to filesystem: %w", err)
-	}
-
-	outPage := format.Page{
-		Id:        current.Filename(""),
-		Filepath:  outFile,
-		MediaType: mediaType,
-		Size:      current.Size(),
+	log.Printf("Summary: %d succeeded, %d failed.", len(results)-failed, failed)
+	log.Indent()
+	for _, line := range strings.Split(strings.TrimRight(sb.String(), "\n"), "\n") {
+		log.Printf("%s", line)
 	}
-
-	return &outPage, processed, nil
+	log.Unindent()
 }
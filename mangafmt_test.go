@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/teerapap/mangafmt/internal/book/format"
+)
+
+func TestDefaultOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	volDir := filepath.Join(dir, "Vol.01")
+	if err := os.Mkdir(volDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pdfFile := filepath.Join(dir, "Vol.02.pdf")
+	cbzFile := filepath.Join(dir, "Vol.03.cbz")
+	outDir := filepath.Join(dir, "out")
+
+	tests := []struct {
+		input     string
+		outputDir string
+		format    format.OutputFormat
+		want      string
+	}{
+		{pdfFile, "", format.EPUB, filepath.Join(dir, "Vol.02.epub")},
+		{pdfFile, "", format.RAW, filepath.Join(dir, "Vol.02")},
+		{cbzFile, "", format.CBZ, filepath.Join(dir, "Vol.03-out.cbz")},
+		{volDir, "", format.EPUB, filepath.Join(dir, "Vol.01.epub")},
+		{volDir, "", format.RAW, filepath.Join(dir, "Vol.01-out")},
+		{pdfFile, outDir, format.EPUB, filepath.Join(outDir, "Vol.02.epub")},
+		{volDir, outDir, format.EPUB, filepath.Join(outDir, "Vol.01.epub")},
+		{volDir, outDir, format.RAW, filepath.Join(outDir, "Vol.01")},
+	}
+	for _, tt := range tests {
+		if got := defaultOutputFile(tt.input, tt.outputDir, tt.format); got != tt.want {
+			t.Errorf("defaultOutputFile(%s, %q, %s) = %s, want %s", tt.input, tt.outputDir, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestBatchOutputFiles(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "out")
+	a := filepath.Join(dir, "a", "book.pdf")
+	b := filepath.Join(dir, "b", "book.pdf")
+	c := filepath.Join(dir, "b", "other.pdf")
+	x := filepath.Join(dir, "x.pdf")
+	y := filepath.Join(dir, "x.cbz")
+
+	outputs, err := batchOutputFiles([]string{a, c}, outDir, format.EPUB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(outDir, "book.epub"), filepath.Join(outDir, "other.epub")}; !slices.Equal(outputs, want) {
+		t.Errorf("got %v, want %v", outputs, want)
+	}
+	if outputs, err := batchOutputFiles([]string{a, b}, "", format.EPUB); err != nil {
+		t.Errorf("got error %v for different directories without output directory", err)
+	} else if len(outputs) != 2 {
+		t.Errorf("got %v, want 2 output files", outputs)
+	}
+
+	tests := []struct {
+		inputs    []string
+		outputDir string
+	}{
+		{[]string{a, b}, outDir},
+		{[]string{x, y}, ""},
+		{[]string{x, c, y}, outDir},
+	}
+	for _, tt := range tests {
+		if _, err := batchOutputFiles(tt.inputs, tt.outputDir, format.EPUB); err == nil {
+			t.Errorf("batchOutputFiles(%v, %q) got no error for the same output file", tt.inputs, tt.outputDir)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/teerapap/mangafmt/internal/book"
+	"github.com/teerapap/mangafmt/internal/book/format"
+	"github.com/teerapap/mangafmt/internal/log"
+)
+
+type loadedPage struct {
+	page *book.Page
+	err  error
+	log  *log.Logger
+}
+
+// pageUnit is a single page or two connected pages which becomes one output page
+type pageUnit struct {
+	index    int
+	pageNo   int // first input page number
+	nextPage int // next input page number after this unit
+	left     *book.Page
+	right    *book.Page // nil if single page
+	align    book.SpreadAlignment
+	log      *log.Logger
+	err      error
+}
+
+type unitResult struct {
+	unit     pageUnit
+	outPages []format.Page
+	removed  bool // blank page is removed
+	err      error
+}
+
+// processPages processes pages in the page range with `jobs` workers.
+// Pages are loaded ahead in order and the double-page spread detection is done in order
+// while the rest of processing is done in parallel. The output pages and logs are in the same order as input pages.
+// It also returns the input page numbers of removed blank pages.
+func processPages(theBook *book.Book, pr *book.PageRange, jobs int) ([]format.Page, []int, error) {
+	jobs = max(1, jobs)
+	pageNos := pr.All()
+	partials := len(pageNos) != theBook.PageCount
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Load pages in background. The window limits the number of loaded pages in memory.
+	window := make(chan struct{}, 2*jobs+2)
+	loaded := make([]chan loadedPage, len(pageNos))
+	for i := range loaded {
+		loaded[i] = make(chan loadedPage, 1)
+	}
+	loadQueue := make(chan int)
+	go func() {
+		defer close(loadQueue)
+		for i := range pageNos {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case loadQueue <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for w := 0; w < jobs; w++ {
+		go func() {
+			for i := range loadQueue {
+				// loading logs are buffered until the page joins its unit
+				pageLog := log.NewBuffer()
+				pageLog.Indent()
+				var page *book.Page
+				err := recovered(func() (err error) {
+					page, err = theBook.LoadPage(pageNos[i], pageLog)
+					return err
+				})
+				loaded[i] <- loadedPage{page, err, pageLog}
+			}
+		}()
+	}
+	// await waits for the page at index i and moves its loading logs to the unit logger
+	await := func(i int, unitLog *log.Logger) (*book.Page, error) {
+		select {
+		case lp := <-loaded[i]:
+			lp.log.MoveTo(unitLog)
+			if lp.err != nil {
+				return nil, fmt.Errorf("loading page %d: %w", pageNos[i], lp.err)
+			}
+			lp.page.SetLogger(unitLog)
+			return lp.page, nil
+		case <-done:
+			return nil, fmt.Errorf("cancelled")
+		}
+	}
+
+	// Group pages into units in order
+	units := make(chan pageUnit)
+	go func() {
+		defer close(units)
+		var next *book.Page // loaded page which is not connected with the previous page
+		for i, index := 0, 0; i < len(pageNos); index++ {
+			pageNo := pageNos[i]
+			unit := pageUnit{index: index, pageNo: pageNo, log: log.NewBuffer()}
+			if partials {
+				unit.log.Printf("Processing page %d....(%d/%d)", pageNo, i+1, len(pageNos))
+			} else {
+				unit.log.Printf("Processing page....(%d/%d)", pageNo, theBook.PageCount)
+			}
+			unit.log.Indent()
+
+			current := next
+			next = nil
+			if current == nil {
+				current, unit.err = await(i, unit.log)
+			}
+			if unit.err == nil {
+				current.SetLogger(unit.log)
+				unit.left = current
+				i += 1
+
+				// Look ahead next page
+				if i < len(pageNos) && pageNos[i] == pageNo+1 && (spreadConfig.Enabled || spreadConfig.Force != nil) {
+					if next, unit.err = await(i, unit.log); unit.err == nil {
+						unit.err = recovered(func() (err error) {
+							unit.left, unit.right, unit.align, err = detectSpread(current, next)
+							return err
+						})
+						if unit.right != nil {
+							// connected with next page
+							next = nil
+							i += 1
+						}
+					}
+				}
+			}
+
+			if unit.err == nil {
+				unit.nextPage = pageNos[i-1] + 1
+			}
+			select {
+			case units <- unit:
+			case <-done:
+				return
+			}
+			if unit.err != nil {
+				return
+			}
+		}
+	}()
+
+	// Process units in parallel
+	results := make(chan unitResult)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for unit := range units {
+				res := unitResult{unit: unit, err: unit.err}
+				if unit.err == nil {
+					res.err = recovered(func() (err error) {
+						res.outPages, res.removed, err = processEachPage(unit)
+						return err
+					})
+				}
+				// release loaded pages
+				<-window
+				if unit.right != nil {
+					<-window
+				}
+				select {
+				case results <- res:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collect results in order
+	outPages := make([]format.Page, 0, len(pageNos))
+	removed := make([]int, 0)
+	pending := make(map[int]unitResult)
+	next := 0
+	for res := range results {
+		pending[res.unit.index] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next += 1
+			if r.err != nil {
+				r.unit.log.Flush()
+				return nil, nil, fmt.Errorf("page %d: %w", r.unit.pageNo, r.err)
+			}
+			if r.removed {
+				removed = append(removed, r.unit.pageNo)
+			}
+			outPages = append(outPages, r.outPages...)
+			r.unit.log.Verbosef("next input page = %d, next output page = %d", r.unit.nextPage, len(outPages))
+			r.unit.log.Unindent()
+			r.unit.log.Flush()
+		}
+	}
+	return outPages, removed, nil
+}
+
+// measureTrimBoxes loads pages in the page range with `jobs` workers and measures their trim boxes
+func measureTrimBoxes(theBook *book.Book, pr *book.PageRange, jobs int) ([]book.TrimSample, error) {
+	pageNos := pr.All()
+	samples := make([]book.TrimSample, len(pageNos))
+	errs := make([]error, len(pageNos))
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(1, jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				errs[i] = recovered(func() error {
+					page, err := theBook.LoadPage(pageNos[i], log.NewBuffer()) // discarded
+					if err != nil {
+						return fmt.Errorf("loading page %d: %w", pageNos[i], err)
+					}
+					defer page.Destroy()
+					box, err := page.TrimBox(trimConfig, fuzzP)
+					if err != nil {
+						return fmt.Errorf("page %d: %w", pageNos[i], err)
+					}
+					samples[i] = book.TrimSample{PageNo: pageNos[i], Size: page.Size(), Box: box}
+					return nil
+				})
+			}
+		}()
+	}
+	for i := range pageNos {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	for i, sample := range samples {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		log.Verbosef("Page %d: trim box %s", sample.PageNo, sample.Box)
+	}
+	return samples, nil
+}
+
+// detectSpread returns left and right pages with their alignment if two pages are double-page spread. Otherwise, right page is nil.
+func detectSpread(current *book.Page, next *book.Page) (*book.Page, *book.Page, book.SpreadAlignment, error) {
+	left, right := current.LeftRight(next)
+	res, err := left.DetectSpread(right, spreadConfig)
+	if err != nil {
+		return nil, nil, res.Alignment, fmt.Errorf("checking if two pages are double-page spread: %w", err)
+	}
+	if res.Connected {
+		return left, right, res.Alignment, nil
+	}
+	return current, nil, res.Alignment, nil
+}
+
+// processEachPage processes the unit into output pages. It returns true if the unit is a removed blank page.
+func processEachPage(unit pageUnit) ([]format.Page, bool, error) {
+	current := unit.left
+	defer current.Destroy()
+
+	if unit.right != nil {
+		// connect two pages
+		defer unit.right.Destroy()
+		connected, err := unit.left.Connect(unit.right, unit.align)
+		if err != nil {
+			return nil, false, fmt.Errorf("connecting two pages: %w", err)
+		}
+		current = connected
+		defer current.Destroy()
+	}
+
+	blank := false
+	if (spreadParity || blankConfig.Remove) && unit.right == nil {
+		var err error
+		if blank, err = current.IsBlank(blankConfig); err != nil {
+			return nil, false, fmt.Errorf("checking blank page: %w", err)
+		}
+		if blank && blankConfig.Remove {
+			unit.log.Printf("[Blank] Removing blank page")
+			return nil, true, nil
+		}
+	}
+
+	// Trim image with fuzz
+	if err := current.Trim(trimConfig, fuzzP); err != nil {
+		return nil, false, fmt.Errorf("trimming page: %w", err)
+	}
+
+	// Split connected pages back into the original pages or a wide page at the gutter
+	var pages []*book.Page
+	if current.OtherPageNo > 0 {
+		pages = current.SplitSpread(spreadConfig.Mode)
+	} else {
+		pages = current.SplitWide(splitConfig, targetSize, fuzzP)
+	}
+
+	outPages := make([]format.Page, 0, len(pages))
+	for _, page := range pages {
+		if page != current {
+			defer page.Destroy()
+		}
+
+		wide := page.Size().Orientation() == book.Landscape
+
+		// Resize page to aspect fit screen
+		if page.OtherPageNo > 0 && spreadConfig.Mode == book.SpreadFit {
+			if err := page.Letterbox(targetSize, resizeConfig); err != nil {
+				return nil, false, fmt.Errorf("letterboxing page to fit to screen: %w", err)
+			}
+		} else if err := page.ResizeToFit(targetSize, rotation, resizeConfig); err != nil {
+			return nil, false, fmt.Errorf("resizing page to fit to screen: %w", err)
+		}
+
+		// Convert to grayscale
+		if err := page.ConvertToGrayscale(grayConfig); err != nil {
+			return nil, false, fmt.Errorf("converting page to grayscale: %w", err)
+		}
+
+		// Write to filesystem
+		outFile, mediaType, err := page.WriteFile(workDir)
+		if err != nil {
+			return nil, false, fmt.Errorf("writing to filesystem: %w", err)
+		}
+
+		outPages = append(outPages, format.Page{
+			Id:        page.Filename(""),
+			Filepath:  outFile,
+			MediaType: mediaType,
+			Size:      page.Size(),
+
+			PageNo:     page.PageNo,
+			SpreadHalf: page.SpreadHalf,
+			Wide:       wide,
+			Blank:      blank && page == current,
+		})
+	}
+
+	return outPages, false, nil
+}
+
+// recovered calls f and returns a panic in f as an error.
+// Workers call it for each task so that a panic fails only the book instead of crashing the whole process.
+func recovered(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Verbosef("%s", debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return f()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecovered(t *testing.T) {
+	if err := recovered(func() error { return nil }); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	want := errors.New("failed")
+	if err := recovered(func() error { return want }); err != want {
+		t.Errorf("got %v, want %v", err, want)
+	}
+	err := recovered(func() error {
+		var pages []int
+		_ = pages[1]
+		return nil
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "panic: ") {
+		t.Errorf("got %v, want a panic error", err)
+	}
+}